@@ -76,6 +76,21 @@ build_libgodeps() {
   done
 }
 
+build_libllgo() {
+  local goc="$1"
+  local gocflags="$2"
+  local destdir="$3"
+  local variantname="$4"
+
+  # libllgo is compiled before libgo, as the driver links it into every
+  # program, including those that libgo's configure script builds.
+  mkdir -p $destdir
+  echo "# Building $variantname libllgo."
+  $goc -no-prefix $GOCFLAGS $gocflags -O2 -fgo-pkgpath=llgo -c -o $destdir/libllgo.o $llgodir/libllgo/*.go
+  rm -f $destdir/libllgo.a
+  ar rcs $destdir/libllgo.a $destdir/libllgo.o
+}
+
 build_libgo_stage() {
   local goc="$1"
   local cflags="$2"
@@ -88,7 +103,8 @@ build_libgo_stage() {
   libgo_cc="$llgo_cc $cflags"
   libgo_wrapped_cc="env REAL_CC=$(echo $libgo_cc | sed -e 's/ /@SPACE@/g') $workdir/cc-wrapper"
 
-  mkdir -p $destdir
+  build_libllgo "$goc" "$gocflags" "$destdir" "$variantname"
+
   echo "# Configuring $variantname libgo."
   (cd $destdir && $gofrontenddir/libgo/configure --disable-multilib --without-libatomic CC="$libgo_wrapped_cc" GOC="$goc -no-prefix $GOCFLAGS $gocflags" > $workdir/${variantname}-config.log 2>&1 || (echo "# Configure failed, see $workdir/${variantname}-config.log" && exit 1))
   echo "# Building $variantname libgo."
@@ -124,9 +140,15 @@ if [ "$bootstrap_type" != "" ]; then
   (cd $llgodir/cmd/cc-wrapper && go build -o $workdir/cc-wrapper)
   (cd $llgodir/cmd/makefilter && go build -o $workdir/makefilter)
 
+  # The driver's TargetOptions shim (cmd/gllgo/targetoptions.cpp) is
+  # compiled against the headers of the LLVM used by the Go bindings.
+  llvm_config="${LLVM_CONFIG:-$(go list -f '{{.Dir}}' llvm.org/llvm/bindings/go/llvm)/workdir/llvm_build/bin/llvm-config}"
+  export CGO_CXXFLAGS="$($llvm_config --cxxflags) $CGO_CXXFLAGS"
+
   # Build a stage1 compiler with gc.
   echo "# Building stage1 compiler."
   (cd $llgodir/cmd/gllgo && go build -o $workdir/gllgo-stage1)
+  (cd $llgodir/utils/llgosize && go build -o $workdir/llgosize)
 
   # Build libgo with the stage1 compiler.
   build_libgo_stage $workdir/gllgo-stage1 "" "" $gofrontend_builddir/libgo-stage1 stage1 "$*"
@@ -147,7 +169,7 @@ if [ "$bootstrap_type" != "" ]; then
 
   if [ "$bootstrap_type" == "full" ] ; then
     # Build libgo with the stage2 compiler.
-    build_libgo_stage $workdir/gllgo-stage2 "" "" $gofrontent_builddir/libgo-stage2 stage2 "$*"
+    build_libgo_stage $workdir/gllgo-stage2 "" "" $gofrontend_builddir/libgo-stage2 stage2 "$*"
 
     # Set up $gllgoflags to use the stage2 libgo.
     gllgoflags="-no-prefix -L$gofrontend_builddir/libgo-stage2 -L$gofrontend_builddir/libgo-stage2/.libs -static-libgo $GOCFLAGS"
@@ -19,6 +19,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/go-llvm/llgo/debug"
 	"github.com/go-llvm/llgo/irgen"
@@ -49,17 +50,35 @@ func initCompiler(opts *driverOptions) (*irgen.Compiler, error) {
 		importPaths = append(importPaths, filepath.Join(opts.prefix, "lib", "go"))
 	}
 	copts := irgen.CompilerOptions{
-		TargetTriple:       opts.triple,
-		GenerateDebug:      opts.generateDebug,
-		DebugPrefixMaps:    opts.debugPrefixMaps,
-		DumpSSA:            opts.dumpSSA,
-		GccgoPath:          opts.gccgoPath,
-		ImportPaths:        importPaths,
-		SanitizerAttribute: opts.sanitizer.getAttribute(),
+		TargetTriple:            opts.triple,
+		GenerateDebug:           opts.generateDebug,
+		DebugPrefixMaps:         opts.debugPrefixMaps,
+		DumpSSA:                 opts.dumpSSA,
+		GccgoPath:               opts.gccgoPath,
+		ImportPaths:             importPaths,
+		SanitizerAttribute:      opts.sanitizer.getAttribute(),
+		DisableFramePointerElim: opts.noOmitFramePointer,
+		UnwindTables:            opts.unwindTables,
+		FastMath:                opts.fastMath,
+		SoftFloat:               opts.softFloat,
+		TrapOverflow:            opts.trapOverflow,
+		PartialIRFile:           opts.partialIRFile,
+		LinkTimeInit:            opts.linkTimeInit,
+		RuntimeHooks:            opts.runtimeHooks,
+		StackRoots:              opts.stackRoots,
+		Vet:                     opts.vet,
 	}
 	if opts.dumpTrace {
 		copts.Logger = log.New(os.Stderr, "", 0)
 	}
+	if !opts.noWarnings {
+		copts.Warnings = func(w irgen.Warning) {
+			fmt.Fprintln(os.Stderr, w)
+		}
+	}
+	if opts.stats != nil {
+		copts.TimePhase = opts.stats.add
+	}
 	return irgen.NewCompiler(copts)
 }
 
@@ -68,6 +87,7 @@ type actionKind int
 const (
 	actionAssemble = actionKind(iota)
 	actionCompile
+	actionCheck
 	actionLink
 	actionPrint
 )
@@ -153,29 +173,47 @@ type driverOptions struct {
 	actions []action
 	output  string
 
-	bprefix         string
-	debugPrefixMaps []debug.PrefixMap
-	dumpSSA         bool
-	dumpTrace       bool
-	emitIR          bool
-	gccgoPath       string
-	generateDebug   bool
-	importPaths     []string
-	libPaths        []string
-	llvmArgs        []string
-	lto             bool
-	optLevel        int
-	pic             bool
-	pieLink         bool
-	pkgpath         string
-	plugins         []string
-	prefix          string
-	sanitizer       sanitizerOptions
-	sizeLevel       int
-	staticLibgcc    bool
-	staticLibgo     bool
-	staticLink      bool
-	triple          string
+	bprefix            string
+	cpu                string
+	dataSections       bool
+	debugPrefixMaps    []debug.PrefixMap
+	dumpDecl           string
+	dumpSSA            bool
+	dumpTrace          bool
+	emitIR             bool
+	features           []string
+	fastMath           bool
+	functionSections   bool
+	gccgoPath          string
+	generateDebug      bool
+	importPaths        []string
+	libPaths           []string
+	linkTimeInit       bool
+	llvmArgs           []string
+	lto                bool
+	noOmitFramePointer bool
+	noWarnings         bool
+	optLevel           int
+	partialIRFile      string
+	pic                bool
+	pieLink            bool
+	pkgpath            string
+	plugins            []string
+	prefix             string
+	runtimeHooks       bool
+	sanitizer          sanitizerOptions
+	sizeLevel          int
+	softFloat          bool
+	stackRoots         bool
+	staticLibgcc       bool
+	staticLibgo        bool
+	staticLink         bool
+	stats              *compileStats
+	trapOverflow       bool
+	triple             string
+	unwindTables       bool
+	vet                bool
+	wholeProgram       bool
 }
 
 func getInstPrefix() (string, error) {
@@ -198,7 +236,9 @@ func parseArguments(args []string) (opts driverOptions, err error) {
 	hasOtherNonFlagInputs := false
 	noPrefix := false
 	actionKind := actionLink
+	march := ""
 	opts.triple = llvm.DefaultTargetTriple()
+	softFloat := -1
 
 	for len(args) > 0 {
 		consumedArgs := 1
@@ -273,6 +313,9 @@ func parseArguments(args []string) (opts driverOptions, err error) {
 		case args[0] == "-c":
 			actionKind = actionCompile
 
+		case args[0] == "-fsyntax-only":
+			actionKind = actionCheck
+
 		case strings.HasPrefix(args[0], "-fcompilerrt-prefix="):
 			opts.sanitizer.crtPrefix = args[0][20:]
 
@@ -283,12 +326,21 @@ func parseArguments(args []string) (opts driverOptions, err error) {
 			}
 			opts.debugPrefixMaps = append(opts.debugPrefixMaps, debug.PrefixMap{split[0], split[1]})
 
+		case strings.HasPrefix(args[0], "-fdump-decl="):
+			opts.dumpDecl = args[0][len("-fdump-decl="):]
+
 		case args[0] == "-fdump-ssa":
 			opts.dumpSSA = true
 
 		case args[0] == "-fdump-trace":
 			opts.dumpTrace = true
 
+		case args[0] == "-fdata-sections":
+			opts.dataSections = true
+
+		case args[0] == "-ffunction-sections":
+			opts.functionSections = true
+
 		case strings.HasPrefix(args[0], "-fgccgo-path="):
 			opts.gccgoPath = args[0][13:]
 
@@ -302,6 +354,27 @@ func parseArguments(args []string) (opts driverOptions, err error) {
 			opts.plugins = append(opts.plugins, args[1])
 			consumedArgs = 2
 
+		case args[0] == "-fomit-frame-pointer":
+			opts.noOmitFramePointer = false
+
+		case args[0] == "-fno-omit-frame-pointer":
+			opts.noOmitFramePointer = true
+
+		case args[0] == "-funwind-tables", args[0] == "-fasynchronous-unwind-tables":
+			opts.unwindTables = true
+
+		case args[0] == "-fno-unwind-tables", args[0] == "-fno-asynchronous-unwind-tables":
+			opts.unwindTables = false
+
+		case args[0] == "-w":
+			opts.noWarnings = true
+
+		case args[0] == "-stats":
+			opts.stats = new(compileStats)
+
+		case args[0] == "-ftrapv":
+			opts.trapOverflow = true
+
 		case args[0] == "-fno-toplevel-reorder":
 			// This is a GCC-specific code generation option. Ignore.
 
@@ -311,6 +384,24 @@ func parseArguments(args []string) (opts driverOptions, err error) {
 		case args[0] == "-flto":
 			opts.lto = true
 
+		case args[0] == "-finit-at-link":
+			opts.linkTimeInit = true
+
+		case args[0] == "-fruntime-hooks":
+			opts.runtimeHooks = true
+
+		case args[0] == "-fgc-stack-roots":
+			opts.stackRoots = true
+
+		case args[0] == "-fvet":
+			opts.vet = true
+
+		case args[0] == "-fwhole-program":
+			opts.wholeProgram = true
+
+		case strings.HasPrefix(args[0], "-fdump-partial-ir="):
+			opts.partialIRFile = args[0][len("-fdump-partial-ir="):]
+
 		case args[0] == "-fPIC":
 			opts.pic = true
 
@@ -338,8 +429,40 @@ func parseArguments(args []string) (opts driverOptions, err error) {
 			opts.llvmArgs = append(opts.llvmArgs, args[1])
 			consumedArgs = 2
 
+		case args[0] == "-ffast-math":
+			opts.fastMath = true
+
+		case args[0] == "-fno-fast-math":
+			opts.fastMath = false
+
+		case args[0] == "-msoft-float", args[0] == "-mfloat-abi=soft":
+			softFloat = 1
+
+		case args[0] == "-mhard-float", args[0] == "-mfloat-abi=hard":
+			softFloat = 0
+
+		case strings.HasPrefix(args[0], "-mcpu="):
+			opts.cpu = args[0][len("-mcpu="):]
+
+		case strings.HasPrefix(args[0], "-march="):
+			march = args[0][len("-march="):]
+
+		case strings.HasPrefix(args[0], "-mattr="):
+			for _, attr := range strings.Split(args[0][len("-mattr="):], ",") {
+				if attr == "" {
+					continue
+				}
+				if attr[0] != '+' && attr[0] != '-' {
+					return opts, fmt.Errorf("invalid target attribute '%s' in '%s'", attr, args[0])
+				}
+				opts.features = append(opts.features, attr)
+			}
+
 		case strings.HasPrefix(args[0], "-m"), args[0] == "-funsafe-math-optimizations", args[0] == "-ffp-contract=off":
 			// TODO(pcc): Handle code generation options.
+			// -funsafe-math-optimizations is passed by libgo's build when
+			// compiling package math, which must not be compiled with
+			// FastMath, as that would fold its NaN and infinity checks.
 
 		case args[0] == "-no-prefix":
 			noPrefix = true
@@ -369,6 +492,16 @@ func parseArguments(args []string) (opts driverOptions, err error) {
 		case args[0] == "-static-libgo":
 			opts.staticLibgo = true
 
+		case args[0] == "-target":
+			if len(args) == 1 {
+				return opts, errors.New("missing triple after '-target'")
+			}
+			opts.triple = args[1]
+			consumedArgs = 2
+
+		case strings.HasPrefix(args[0], "--target="):
+			opts.triple = args[0][len("--target="):]
+
 		default:
 			return opts, fmt.Errorf("unrecognized command line option '%s'", args[0])
 		}
@@ -387,6 +520,36 @@ func parseArguments(args []string) (opts driverOptions, err error) {
 		}
 	}
 
+	if march != "" {
+		opts.triple, opts.cpu, err = applyMarch(opts.triple, opts.cpu, march)
+		if err != nil {
+			return opts, err
+		}
+	}
+
+	// An explicit soft-float target attribute takes precedence over the
+	// -m flags and the triple, and is passed through as is.
+	hasSoftFloatAttr := false
+	for _, attr := range opts.features {
+		switch attr {
+		case "+soft-float":
+			softFloat = 1
+			hasSoftFloatAttr = true
+		case "-soft-float":
+			softFloat = 0
+			hasSoftFloatAttr = true
+		}
+	}
+	switch softFloat {
+	case -1:
+		opts.softFloat = isSoftFloatTriple(opts.triple)
+	case 1:
+		opts.softFloat = true
+	}
+	if opts.softFloat && !hasSoftFloatAttr {
+		opts.features = append(opts.features, "+soft-float")
+	}
+
 	if opts.sanitizer.crtPrefix == "" {
 		opts.sanitizer.crtPrefix = opts.prefix
 	}
@@ -405,7 +568,7 @@ func parseArguments(args []string) (opts driverOptions, err error) {
 		}
 		opts.actions = append(opts.actions, action{actionLink, otherInputs})
 
-	case actionCompile, actionAssemble:
+	case actionCompile, actionAssemble, actionCheck:
 		if len(goInputs) != 0 {
 			opts.actions = []action{action{actionKind, goInputs}}
 		}
@@ -433,6 +596,74 @@ func parseArguments(args []string) (opts driverOptions, err error) {
 	return opts, nil
 }
 
+// applyMarch returns the triple and CPU selected by the option -march=march
+// for the target triple, given the CPU selected by -mcpu, if any. On x86 and
+// MIPS, the architecture names accepted by GCC are also LLVM CPU names, and
+// select the CPU unless -mcpu was given. On ARM, they name a sub-architecture,
+// which LLVM takes from the triple.
+func applyMarch(triple, cpu, march string) (string, string, error) {
+	parts := strings.SplitN(triple, "-", 2)
+	arch := parts[0]
+	switch {
+	case arch == "x86_64", len(arch) == 4 && arch[0] == 'i' && arch[2:] == "86",
+		strings.HasPrefix(arch, "mips"):
+		if cpu == "" {
+			cpu = march
+		}
+		return triple, cpu, nil
+
+	case strings.HasPrefix(arch, "arm") && arch != "arm64", strings.HasPrefix(arch, "thumb"):
+		subarch, ok := armSubarch(march)
+		if !ok {
+			return "", "", fmt.Errorf("invalid ARM architecture in '-march=%s' (use '-mcpu' to select a CPU)", march)
+		}
+		prefix := arch[:strings.IndexAny(arch+"v", "v0123456789")]
+		parts[0] = prefix + subarch
+		return strings.Join(parts, "-"), cpu, nil
+	}
+	return "", "", fmt.Errorf("'-march=%s' is not supported for target '%s' (use '-mcpu' to select a CPU)", march, triple)
+}
+
+// armSubarch returns the triple sub-architecture named by the ARM
+// architecture name march, as accepted by GCC: for example, "v7" for
+// "armv7-a" and "v7m" for "armv7-m".
+func armSubarch(march string) (string, bool) {
+	if !strings.HasPrefix(march, "armv") || len(march) == 4 || march[4] < '0' || march[4] > '9' {
+		return "", false
+	}
+	subarch := march[3:]
+	if i := strings.IndexRune(subarch, '-'); i != -1 {
+		profile := subarch[i+1:]
+		subarch = subarch[:i]
+		switch profile {
+		case "a":
+		case "r", "m":
+			subarch += profile
+		default:
+			return "", false
+		}
+	}
+	return subarch, true
+}
+
+// isSoftFloatTriple reports whether the target described by triple lacks a
+// floating point unit (or uses a soft float ABI) by default, in which case
+// floating point operations must be lowered to library calls.
+func isSoftFloatTriple(triple string) bool {
+	parts := strings.Split(triple, "-")
+	arch, env := parts[0], parts[len(parts)-1]
+	switch {
+	case arch == "avr", arch == "msp430":
+		return true
+	case strings.HasPrefix(arch, "thumbv6m"), strings.HasPrefix(arch, "thumbv7m"),
+		strings.HasPrefix(arch, "thumbv8m.base"):
+		return true
+	case strings.HasPrefix(arch, "arm") && arch != "arm64", strings.HasPrefix(arch, "thumb"):
+		return len(parts) > 1 && strings.HasSuffix(env, "eabi")
+	}
+	return false
+}
+
 func runPasses(opts *driverOptions, tm llvm.TargetMachine, m llvm.Module) {
 	fpm := llvm.NewFunctionPassManagerForModule(m)
 	defer fpm.Dispose()
@@ -452,17 +683,14 @@ func runPasses(opts *driverOptions, tm llvm.TargetMachine, m llvm.Module) {
 	tm.AddAnalysisPasses(mpm)
 	tm.AddAnalysisPasses(fpm)
 
-	mpm.AddVerifierPass()
-	fpm.AddVerifierPass()
-
 	pmb.Populate(mpm)
 	pmb.PopulateFunc(fpm)
 
-	if opts.optLevel == 0 {
-		// Remove references (via the descriptor) to dead functions,
-		// for compatibility with other compilers.
-		mpm.AddGlobalDCEPass()
-	}
+	// Remove dead functions and globals (in particular, unreferenced type
+	// descriptors and their algorithm functions). At -O0 this also removes
+	// references (via the descriptor) to dead functions, for compatibility
+	// with other compilers.
+	mpm.AddGlobalDCEPass()
 
 	opts.sanitizer.addPasses(mpm, fpm)
 
@@ -475,6 +703,50 @@ func runPasses(opts *driverOptions, tm llvm.TargetMachine, m llvm.Module) {
 	mpm.Run(m)
 }
 
+func createTargetMachine(opts *driverOptions) (llvm.TargetMachine, error) {
+	target, err := llvm.GetTargetFromTriple(opts.triple)
+	if err != nil {
+		return llvm.TargetMachine{}, err
+	}
+
+	optLevel := [...]llvm.CodeGenOptLevel{
+		llvm.CodeGenLevelNone,
+		llvm.CodeGenLevelLess,
+		llvm.CodeGenLevelDefault,
+		llvm.CodeGenLevelAggressive,
+	}[opts.optLevel]
+
+	relocMode := llvm.RelocStatic
+	if opts.pic {
+		relocMode = llvm.RelocPIC
+	}
+
+	features := strings.Join(opts.features, ",")
+	tm := target.CreateTargetMachine(opts.triple, opts.cpu, features, optLevel,
+		relocMode, llvm.CodeModelDefault)
+
+	// Place each function and global in its own section so that the linker
+	// can discard those that are unreferenced.
+	setFunctionSections(tm, opts.functionSections)
+	setDataSections(tm, opts.dataSections)
+	return tm, nil
+}
+
+// getModuleMetadataInlineAsm returns inline assembly creating the sections
+// that hold the export data and initialization graph of module.
+func getModuleMetadataInlineAsm(module *irgen.Module) string {
+	var asm string
+	if module.ExportData != nil {
+		asm += getMetadataSectionInlineAsm(irgen.ExportDataSection)
+		asm += getDataInlineAsm(module.ExportData)
+	}
+	if module.InitGraph != nil {
+		asm += getMetadataSectionInlineAsm(irgen.InitGraphSection)
+		asm += getDataInlineAsm(module.InitGraph)
+	}
+	return asm
+}
+
 func getMetadataSectionInlineAsm(name string) string {
 	// ELF: creates a non-allocated excluded section.
 	return ".section \"" + name + "\", \"e\"\n"
@@ -530,6 +802,56 @@ func getVariantDir(opts *driverOptions) string {
 	}
 }
 
+// findABIMismatch searches linker output for an undefined reference to a
+// package ABI symbol, returning the mangled path of the package that was
+// compiled with an incompatible version of llgo, or "" if there is none.
+func findABIMismatch(out []byte) string {
+	for _, line := range strings.Split(string(out), "\n") {
+		if !strings.Contains(line, "undefined reference") {
+			continue
+		}
+		i := strings.Index(line, irgen.ABISymbolInfix)
+		if i == -1 {
+			continue
+		}
+		start := strings.LastIndexAny(line[:i], "`' ") + 1
+		return line[start:i]
+	}
+	return ""
+}
+
+// findDuplicateSymbol searches linker output for a multiple definition
+// error, returning the name of the symbol concerned, or "" if there is none.
+func findDuplicateSymbol(out []byte) string {
+	const marker = "multiple definition of "
+	for _, line := range strings.Split(string(out), "\n") {
+		i := strings.Index(line, marker)
+		if i == -1 {
+			continue
+		}
+		sym := strings.TrimLeft(line[i+len(marker):], "`'\"")
+		if end := strings.IndexAny(sym, "`'\""); end != -1 {
+			sym = sym[:end]
+		}
+		return sym
+	}
+	return ""
+}
+
+// dumpDecl writes the unoptimized LLVM IR generated for the package-level
+// declaration opts.dumpDecl to output.
+func dumpDecl(compiler *irgen.Compiler, opts *driverOptions, inputs []string, output string) error {
+	ir, err := compiler.CompileDecl(inputs, opts.pkgpath, opts.dumpDecl)
+	if err != nil {
+		return err
+	}
+	if output == "-" {
+		_, err = os.Stdout.WriteString(ir)
+		return err
+	}
+	return ioutil.WriteFile(output, []byte(ir), 0666)
+}
+
 func performAction(opts *driverOptions, kind actionKind, inputs []string, output string) error {
 	switch kind {
 	case actionPrint:
@@ -549,41 +871,63 @@ func performAction(opts *driverOptions, kind actionKind, inputs []string, output
 			panic("unexpected print command")
 		}
 
-	case actionCompile, actionAssemble:
+	case actionCheck:
 		compiler, err := initCompiler(opts)
 		if err != nil {
 			return err
 		}
+		defer compiler.Dispose()
 
-		module, err := compiler.Compile(inputs, opts.pkgpath)
+		checker, err := compiler.NewChecker()
 		if err != nil {
 			return err
 		}
+		defer checker.Dispose()
 
-		defer module.Dispose()
+		return checker.Check(inputs, opts.pkgpath, nil)
+
+	case actionCompile, actionAssemble:
+		opts.stats.reset()
+		defer opts.stats.print(os.Stderr)
 
-		target, err := llvm.GetTargetFromTriple(opts.triple)
+		compiler, err := initCompiler(opts)
 		if err != nil {
 			return err
 		}
+		defer compiler.Dispose()
 
-		optLevel := [...]llvm.CodeGenOptLevel{
-			llvm.CodeGenLevelNone,
-			llvm.CodeGenLevelLess,
-			llvm.CodeGenLevelDefault,
-			llvm.CodeGenLevelAggressive,
-		}[opts.optLevel]
+		if opts.dumpDecl != "" {
+			return dumpDecl(compiler, opts, inputs, output)
+		}
 
-		relocMode := llvm.RelocStatic
-		if opts.pic {
-			relocMode = llvm.RelocPIC
+		var module *irgen.Module
+		if opts.wholeProgram {
+			module, err = compiler.CompileProgram(inputs)
+		} else {
+			module, err = compiler.Compile(inputs, opts.pkgpath)
+		}
+		if err != nil {
+			return err
 		}
 
-		tm := target.CreateTargetMachine(opts.triple, "", "", optLevel,
-			relocMode, llvm.CodeModelDefault)
+		defer module.Dispose()
+
+		tm, err := createTargetMachine(opts)
+		if err != nil {
+			return err
+		}
 		defer tm.Dispose()
 
+		start := time.Now()
+		if err := llvm.VerifyModule(module.Module, llvm.ReturnStatusAction); err != nil {
+			return err
+		}
+		opts.stats.since("verify", start)
+
+		start = time.Now()
 		runPasses(opts, tm, module.Module)
+		opts.stats.since("optimize", start)
+		defer opts.stats.since("emit", time.Now())
 
 		var file *os.File
 		if output == "-" {
@@ -598,11 +942,7 @@ func performAction(opts *driverOptions, kind actionKind, inputs []string, output
 
 		switch {
 		case !opts.lto && !opts.emitIR:
-			if module.ExportData != nil {
-				asm := getMetadataSectionInlineAsm(".go_export")
-				asm += getDataInlineAsm(module.ExportData)
-				module.Module.SetInlineAsm(asm)
-			}
+			module.Module.SetInlineAsm(getModuleMetadataInlineAsm(module))
 
 			fileType := llvm.AssemblyFile
 			if kind == actionCompile {
@@ -631,10 +971,7 @@ func performAction(opts *driverOptions, kind actionKind, inputs []string, output
 			defer outmodule.Dispose()
 			asm := getMetadataSectionInlineAsm(".llvmbc")
 			asm += getDataInlineAsm(bcmb.Bytes())
-			if module.ExportData != nil {
-				asm += getMetadataSectionInlineAsm(".go_export")
-				asm += getDataInlineAsm(module.ExportData)
-			}
+			asm += getModuleMetadataInlineAsm(module)
 			outmodule.SetInlineAsm(asm)
 
 			fileType := llvm.AssemblyFile
@@ -678,6 +1015,12 @@ func performAction(opts *driverOptions, kind actionKind, inputs []string, output
 		if opts.staticLibgcc {
 			args = append(args, "-static-libgcc")
 		}
+		if opts.functionSections || opts.dataSections {
+			args = append(args, "-Wl,--gc-sections")
+		}
+		if opts.runtimeHooks {
+			args = append(args, irgen.RuntimeHookLinkerFlags()...)
+		}
 		for _, p := range opts.libPaths {
 			args = append(args, "-L", p)
 		}
@@ -685,6 +1028,14 @@ func performAction(opts *driverOptions, kind actionKind, inputs []string, output
 			args = append(args, "-I", p)
 		}
 		args = append(args, inputs...)
+		if opts.linkTimeInit {
+			initMain, err := compileInitMain(opts, inputs)
+			if err != nil {
+				return err
+			}
+			defer os.Remove(initMain)
+			args = append(args, initMain)
+		}
 		var linkerPath string
 		if opts.gccgoPath == "" {
 			// TODO(pcc): See if we can avoid calling gcc here.
@@ -700,7 +1051,8 @@ func performAction(opts *driverOptions, kind actionKind, inputs []string, output
 				}
 			}
 
-			args = append(args, "-lgobegin")
+			// libllgo calls into libgo, so must precede it.
+			args = append(args, "-lgobegin", "-lllgo")
 			if opts.staticLibgo {
 				args = append(args, "-Wl,-Bstatic", "-lgo", "-Wl,-Bdynamic", "-lpthread", "-lm")
 			} else {
@@ -708,6 +1060,15 @@ func performAction(opts *driverOptions, kind actionKind, inputs []string, output
 			}
 		} else {
 			linkerPath = opts.gccgoPath
+
+			// libllgo is not in gccgo's library search path, and adding
+			// llgo's library directory to it would also make gccgo link
+			// llgo's libgo, so the installed archive is named directly.
+			if opts.prefix != "" {
+				args = append(args, filepath.Join(opts.prefix, "lib", getVariantDir(opts), "libllgo.a"))
+			} else {
+				args = append(args, "-lllgo")
+			}
 			if opts.staticLibgo {
 				args = append(args, "-static-libgo")
 			}
@@ -719,6 +1080,12 @@ func performAction(opts *driverOptions, kind actionKind, inputs []string, output
 		out, err := cmd.CombinedOutput()
 		if err != nil {
 			os.Stderr.Write(out)
+			if pkg := findABIMismatch(out); pkg != "" {
+				return fmt.Errorf("package %s compiled with incompatible llgo version (want ABI %s); recompile it", pkg, irgen.ABIVersion())
+			}
+			if sym := findDuplicateSymbol(out); sym != "" {
+				return fmt.Errorf("duplicate definitions of symbol %s (%s)", sym, irgen.DescribeSymbol(sym))
+			}
 		}
 		return err
 
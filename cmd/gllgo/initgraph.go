@@ -0,0 +1,85 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/go-llvm/llgo/irgen"
+	"llvm.org/llvm/bindings/go/llvm"
+)
+
+// compileInitMain compiles the __go_init_main function of a program whose
+// package main was compiled with -finit-at-link, from the initialization
+// graphs recorded in the objects and archives among inputs, and returns
+// the name of the resulting object file, which the caller must remove.
+func compileInitMain(opts *driverOptions, inputs []string) (string, error) {
+	var nodes []irgen.InitNode
+	for _, input := range inputs {
+		graphs, err := readInitGraphs(input)
+		if err != nil {
+			return "", fmt.Errorf("%s: %v", input, err)
+		}
+		for _, graph := range graphs {
+			n, err := irgen.DecodeInitGraph(graph)
+			if err != nil {
+				return "", fmt.Errorf("%s: %v", input, err)
+			}
+			nodes = append(nodes, n...)
+		}
+	}
+
+	compiler, err := initCompiler(opts)
+	if err != nil {
+		return "", err
+	}
+	defer compiler.Dispose()
+
+	module, err := compiler.CompileInitMain(nodes)
+	if err != nil {
+		return "", err
+	}
+	defer module.Dispose()
+
+	tm, err := createTargetMachine(opts)
+	if err != nil {
+		return "", err
+	}
+	defer tm.Dispose()
+
+	mb, err := tm.EmitToMemoryBuffer(module.Module, llvm.ObjectFile)
+	if err != nil {
+		return "", err
+	}
+	defer mb.Dispose()
+
+	tmpfile, err := ioutil.TempFile("", "llgo")
+	if err != nil {
+		return "", err
+	}
+	tmpfile.Close()
+	if err := os.Remove(tmpfile.Name()); err != nil {
+		return "", err
+	}
+	output := tmpfile.Name() + ".o"
+	if err := ioutil.WriteFile(output, mb.Bytes(), 0666); err != nil {
+		return "", err
+	}
+	return output, nil
+}
+
+// readInitGraphs returns the contents of the initialization graph sections
+// of the object file, or of the objects in the archive, named by input.
+// Inputs that are not files, or are neither ELF objects nor archives (such
+// as linker flags and C source files), have none.
+func readInitGraphs(input string) ([][]byte, error) {
+	data, err := ioutil.ReadFile(input)
+	if err != nil {
+		return nil, nil
+	}
+	return irgen.ReadSections(data, irgen.InitGraphSection)
+}
@@ -0,0 +1,78 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"syscall"
+	"time"
+)
+
+// compileStats records the time spent in each phase of a compile action,
+// for the -stats flag. A nil *compileStats records nothing.
+type compileStats struct {
+	start  time.Time
+	phases []phaseTime
+}
+
+type phaseTime struct {
+	phase string
+	d     time.Duration
+}
+
+// reset discards the recorded phases, and restarts the total time.
+func (s *compileStats) reset() {
+	if s == nil {
+		return
+	}
+	s.start = time.Now()
+	s.phases = nil
+}
+
+// add records d as spent in phase.
+func (s *compileStats) add(phase string, d time.Duration) {
+	if s == nil {
+		return
+	}
+	s.phases = append(s.phases, phaseTime{phase, d})
+}
+
+// since records the time since start as spent in phase.
+func (s *compileStats) since(phase string, start time.Time) {
+	s.add(phase, time.Since(start))
+}
+
+// print writes the recorded phases, the total time and the memory used
+// by the process to w.
+func (s *compileStats) print(w io.Writer) {
+	if s == nil {
+		return
+	}
+	fmt.Fprintln(w, "llgo: compile statistics:")
+	for _, p := range s.phases {
+		fmt.Fprintf(w, "  %-40s %12.3fms\n", p.phase, p.d.Seconds()*1000)
+	}
+	fmt.Fprintf(w, "  %-40s %12.3fms\n", "total", time.Since(s.start).Seconds()*1000)
+
+	const mb = 1 << 20
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	fmt.Fprintf(w, "  Go heap: %.1fMB allocated, %.1fMB obtained from system\n",
+		float64(ms.TotalAlloc)/mb, float64(ms.Sys)/mb)
+
+	// The peak resident set size includes memory allocated by LLVM,
+	// which the Go heap statistics do not.
+	var ru syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &ru); err == nil {
+		maxrss := float64(ru.Maxrss)
+		if runtime.GOOS != "darwin" {
+			// Maxrss is in kilobytes, except on Darwin.
+			maxrss *= 1024
+		}
+		fmt.Fprintf(w, "  peak RSS: %.1fMB\n", maxrss/mb)
+	}
+}
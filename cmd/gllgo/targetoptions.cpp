@@ -0,0 +1,24 @@
+//===- targetoptions.cpp - TargetOptions bindings for the llgo driver -----===//
+//
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+//
+//===----------------------------------------------------------------------===//
+
+#include "llvm-c/TargetMachine.h"
+#include "llvm/Target/TargetMachine.h"
+
+using namespace llvm;
+
+static TargetMachine *unwrapTargetMachine(LLVMTargetMachineRef TM) {
+  return reinterpret_cast<TargetMachine *>(TM);
+}
+
+extern "C" void llgoSetFunctionSections(LLVMTargetMachineRef TM, int Enable) {
+  unwrapTargetMachine(TM)->Options.FunctionSections = Enable;
+}
+
+extern "C" void llgoSetDataSections(LLVMTargetMachineRef TM, int Enable) {
+  unwrapTargetMachine(TM)->Options.DataSections = Enable;
+}
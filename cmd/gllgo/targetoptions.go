@@ -0,0 +1,47 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+// The LLVM C API does not expose TargetOptions, so the options that the
+// driver needs are set by a small C++ shim. The include paths for LLVM's
+// headers are taken from CGO_CXXFLAGS, which bootstrap.sh sets from
+// llvm-config.
+
+/*
+#include "llvm-c/TargetMachine.h"
+
+void llgoSetFunctionSections(LLVMTargetMachineRef tm, int enable);
+void llgoSetDataSections(LLVMTargetMachineRef tm, int enable);
+*/
+import "C"
+
+import (
+	"unsafe"
+
+	"llvm.org/llvm/bindings/go/llvm"
+)
+
+func targetMachineRef(tm llvm.TargetMachine) C.LLVMTargetMachineRef {
+	return C.LLVMTargetMachineRef(unsafe.Pointer(tm.C))
+}
+
+func boolToCInt(b bool) C.int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// setFunctionSections sets whether tm emits each function into its own
+// section, as with -ffunction-sections.
+func setFunctionSections(tm llvm.TargetMachine, enable bool) {
+	C.llgoSetFunctionSections(targetMachineRef(tm), boolToCInt(enable))
+}
+
+// setDataSections sets whether tm emits each global variable into its own
+// section, as with -fdata-sections.
+func setDataSections(tm llvm.TargetMachine, enable bool) {
+	C.llgoSetDataSections(targetMachineRef(tm), boolToCInt(enable))
+}
@@ -181,9 +181,10 @@ func (d *DIBuilder) SetLocation(b llvm.Builder, pos token.Pos) {
 		diFile := d.builder.CreateFile(d.remapFilePath(position.Filename), "")
 		d.lb = d.builder.CreateLexicalBlockFile(d.scope(), diFile, 0)
 	}
-	b.SetCurrentDebugLocation(llvm.MDNode([]llvm.Value{
-		llvm.ConstInt(llvm.Int32Type(), uint64(position.Line), false),
-		llvm.ConstInt(llvm.Int32Type(), uint64(position.Column), false),
+	ctx := d.module.Context()
+	b.SetCurrentDebugLocation(ctx.MDNode([]llvm.Value{
+		llvm.ConstInt(ctx.Int32Type(), uint64(position.Line), false),
+		llvm.ConstInt(ctx.Int32Type(), uint64(position.Column), false),
 		d.scope(),
 		llvm.Value{},
 	}))
@@ -192,20 +193,21 @@ func (d *DIBuilder) SetLocation(b llvm.Builder, pos token.Pos) {
 // Finalize must be called after all compilation units are translated,
 // generating the final debug metadata for the module.
 func (d *DIBuilder) Finalize() {
+	ctx := d.module.Context()
 	d.module.AddNamedMetadataOperand(
 		"llvm.module.flags",
-		llvm.MDNode([]llvm.Value{
-			llvm.ConstInt(llvm.Int32Type(), 2, false), // Warn on mismatch
-			llvm.MDString("Dwarf Version"),
-			llvm.ConstInt(llvm.Int32Type(), 4, false),
+		ctx.MDNode([]llvm.Value{
+			llvm.ConstInt(ctx.Int32Type(), 2, false), // Warn on mismatch
+			ctx.MDString("Dwarf Version"),
+			llvm.ConstInt(ctx.Int32Type(), 4, false),
 		}),
 	)
 	d.module.AddNamedMetadataOperand(
 		"llvm.module.flags",
-		llvm.MDNode([]llvm.Value{
-			llvm.ConstInt(llvm.Int32Type(), 1, false), // Error on mismatch
-			llvm.MDString("Debug Info Version"),
-			llvm.ConstInt(llvm.Int32Type(), 1, false),
+		ctx.MDNode([]llvm.Value{
+			llvm.ConstInt(ctx.Int32Type(), 1, false), // Error on mismatch
+			ctx.MDString("Debug Info Version"),
+			llvm.ConstInt(ctx.Int32Type(), 1, false),
 		}),
 	)
 	d.builder.Finalize()
@@ -338,7 +340,7 @@ func (d *DIBuilder) descriptorStruct(t *types.Struct, name string) llvm.Value {
 
 func (d *DIBuilder) descriptorNamed(t *types.Named) llvm.Value {
 	// Create a placeholder for the named type, to terminate cycles.
-	placeholder := llvm.MDNode(nil)
+	placeholder := d.module.Context().MDNode(nil)
 	d.types.Set(t, placeholder)
 	var diFile llvm.Value
 	var line int
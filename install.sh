@@ -17,12 +17,15 @@ cp $workdir/gllgo-stage3 "$prefix/bin/llgo"
 cp $llgodir/llgo-go.sh "$prefix/bin/llgo-go"
 chmod +x "$prefix/bin/llgo-go"
 
-# Install libgo. If we did a quick bootstrap, only the stage1 libgo will exist.
+# Install libgo and libllgo. If we did a quick bootstrap, only the stage1
+# libraries will exist.
 if [ -d "$gofrontend_builddir/libgo-stage2" ] ; then
-  make -C $gofrontend_builddir/libgo-stage2 install "prefix=$prefix"
+  libgo_builddir=$gofrontend_builddir/libgo-stage2
 else
-  make -C $gofrontend_builddir/libgo-stage1 install "prefix=$prefix"
+  libgo_builddir=$gofrontend_builddir/libgo-stage1
 fi
+make -C $libgo_builddir install "prefix=$prefix"
+cp $libgo_builddir/libllgo.a "$prefix/lib/"
 
 # Install the build variant libraries, but not the export data, which is shared
 # between variants.
@@ -30,6 +33,10 @@ for i in $workdir/gofrontend_build_*/libgo ; do
   if [ -d $i ] ; then
     make -C $i install-toolexeclibLIBRARIES install-toolexeclibLTLIBRARIES \
       "prefix=$prefix"
+    variant=${i#$workdir/gofrontend_build_}
+    variant=${variant%/libgo}
+    mkdir -p "$prefix/lib/llvm-$variant.0"
+    cp $i/libllgo.a "$prefix/lib/llvm-$variant.0/"
   fi
 done
 
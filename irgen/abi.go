@@ -0,0 +1,217 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package irgen
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/types"
+	"llvm.org/llvm/bindings/go/llvm"
+)
+
+// ABIVersion returns a string identifying the ABI of the code generated by
+// this compiler. Packages compiled with different ABI versions must not be
+// linked together.
+func ABIVersion() string {
+	h := sha1.New()
+	h.Write([]byte("llgo " + version + " " + goVersion))
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// ABISymbolInfix is the part of a package's ABI symbol name following the
+// mangled package path. The driver uses it to recognise link errors caused
+// by packages compiled with an incompatible version of llgo.
+const ABISymbolInfix = "..llgo_abi."
+
+// abiSymbolName returns the name of the symbol defined by the package with
+// the given path when compiled with this compiler's ABI version.
+func (c *compiler) abiSymbolName(pkgpath string) string {
+	var b bytes.Buffer
+	c.types.mc.manglePackagePath(pkgpath, &b)
+	b.WriteString(ABISymbolInfix)
+	b.WriteString(ABIVersion())
+	return b.String()
+}
+
+// emitABISymbols defines the ABI symbol for each package in pkgs, and
+// references the ABI symbols of each package they import from outside
+// pkgs, so that linking against a package compiled with an incompatible
+// version of llgo fails with an undefined symbol rather than producing a
+// program that misbehaves at run time.
+//
+// When targeting gccgo's libgo, imported packages were not compiled by
+// llgo and so do not define ABI symbols; in that case, none are
+// referenced.
+func (c *compiler) emitABISymbols(pkgs []*ssa.Package) {
+	i8 := c.llvmtypes.ctx.Int8Type()
+	// seen records the packages that are defined or already referenced.
+	seen := make(map[string]bool, len(pkgs))
+	for _, pkg := range pkgs {
+		seen[pkg.Object.Path()] = true
+		if pkg.Object.Path() != "main" {
+			sym := llvm.AddGlobal(c.module.Module, i8, c.abiSymbolName(pkg.Object.Path()))
+			sym.SetInitializer(llvm.ConstNull(i8))
+			sym.SetGlobalConstant(true)
+		}
+	}
+	if c.GccgoPath != "" {
+		return
+	}
+
+	i8ptr := llvm.PointerType(i8, 0)
+	var refs []llvm.Value
+	for _, pkg := range pkgs {
+		for _, imp := range pkg.Object.Imports() {
+			if imp.Path() == "unsafe" || seen[imp.Path()] {
+				continue
+			}
+			seen[imp.Path()] = true
+			ref := llvm.AddGlobal(c.module.Module, i8, c.abiSymbolName(imp.Path()))
+			ref.SetGlobalConstant(true)
+			refs = append(refs, llvm.ConstBitCast(ref, i8ptr))
+		}
+	}
+	if len(refs) == 0 {
+		return
+	}
+
+	// Add the references to llvm.used, so they survive optimization.
+	c.addUsedGlobals(refs)
+}
+
+// addUsedGlobals adds values, which must be of type i8*, to the module's
+// llvm.used array. If the module already has the array, it is replaced by
+// one holding both its elements and values, as a second global named
+// llvm.used would be renamed, and so ignored.
+func (c *compiler) addUsedGlobals(values []llvm.Value) {
+	if used := c.module.NamedGlobal("llvm.used"); !used.IsNil() {
+		init := used.Initializer()
+		elems := make([]llvm.Value, 0, init.OperandsCount()+len(values))
+		for i := 0; i < init.OperandsCount(); i++ {
+			elems = append(elems, init.Operand(i))
+		}
+		values = append(elems, values...)
+		used.EraseFromParentAsGlobal()
+	}
+	i8ptr := llvm.PointerType(c.llvmtypes.ctx.Int8Type(), 0)
+	init := llvm.ConstArray(i8ptr, values)
+	used := llvm.AddGlobal(c.module.Module, init.Type(), "llvm.used")
+	used.SetInitializer(init)
+	used.SetLinkage(llvm.AppendingLinkage)
+	used.SetSection("llvm.metadata")
+}
+
+// exportDataChecksum returns the checksum recorded in a package's export
+// data, which covers both the export data itself and the ABI version of
+// the compiler that produced it.
+func exportDataChecksum(exportData []byte) string {
+	h := sha1.New()
+	h.Write(exportData)
+	h.Write([]byte(ABIVersion()))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// exportDataChecksumPrefix begins the line of export data that records its
+// checksum. It is the last line written by buildExportData.
+var exportDataChecksumPrefix = []byte("checksum ")
+
+// exportDataChecksumMarker begins the checksums that llgo records in export
+// data. gccgo records a checksum of its own with the same directive, which
+// is not checked, as it does not cover llgo's ABI version. The marker keeps
+// the checksum a single identifier, which gccgoimporter accepts.
+const exportDataChecksumMarker = "llgo_"
+
+// verifyExportData reports an error if the export data in data records a
+// checksum that does not match its contents, as happens if the export data
+// was modified or truncated, or was produced by a compiler with a different
+// ABI version. Only checksums recorded by llgo are checked; export data
+// produced by gccgo, or without a checksum, is accepted.
+func verifyExportData(data []byte) error {
+	i := bytes.LastIndex(data, exportDataChecksumPrefix)
+	if i == -1 || i != 0 && data[i-1] != '\n' {
+		return nil
+	}
+	line := data[i+len(exportDataChecksumPrefix):]
+	if !bytes.HasPrefix(line, []byte(exportDataChecksumMarker)) {
+		return nil
+	}
+	line = line[len(exportDataChecksumMarker):]
+	end := bytes.IndexByte(line, ';')
+	if end == -1 {
+		return fmt.Errorf("malformed export data checksum")
+	}
+	if recorded := string(line[:end]); recorded != exportDataChecksum(data[:i]) {
+		return fmt.Errorf("export data checksum mismatch; the package was modified or compiled by a different version of llgo, and must be recompiled")
+	}
+	return nil
+}
+
+// checksumImporter returns an importer that verifies the checksum of a
+// package's export data, found in searchpaths as gccgoimporter finds it,
+// before importing the package with importer.
+func checksumImporter(importer types.Importer, searchpaths []string) types.Importer {
+	return func(imports map[string]*types.Package, pkgpath string) (*types.Package, error) {
+		if pkgpath != "unsafe" && imports[pkgpath] == nil {
+			if err := verifyImportedExportData(searchpaths, pkgpath); err != nil {
+				return nil, err
+			}
+		}
+		return importer(imports, pkgpath)
+	}
+}
+
+// verifyImportedExportData verifies the export data of the package with
+// path pkgpath. Missing export data is left to the importer to report.
+func verifyImportedExportData(searchpaths []string, pkgpath string) error {
+	filename := findExportFile(searchpaths, pkgpath)
+	if filename == "" {
+		return nil
+	}
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil
+	}
+	sections, err := ReadSections(data, ExportDataSection)
+	if err != nil {
+		return fmt.Errorf("%s: %v", filename, err)
+	}
+	if sections == nil {
+		// A file of export data rather than an object.
+		sections = [][]byte{data}
+	}
+	if err := verifyExportData(sections[0]); err != nil {
+		return fmt.Errorf("%s: %v", filename, err)
+	}
+	return nil
+}
+
+// findExportFile returns the name of the file holding the export data of
+// the package with path pkgpath, searching the same files in the same order
+// as gccgoimporter, or "" if there is none.
+func findExportFile(searchpaths []string, pkgpath string) string {
+	for _, spath := range searchpaths {
+		pkgfullpath := filepath.Join(spath, pkgpath)
+		pkgdir, name := filepath.Split(pkgfullpath)
+		for _, filename := range [...]string{
+			pkgfullpath,
+			pkgfullpath + ".gox",
+			pkgdir + "lib" + name + ".so",
+			pkgdir + "lib" + name + ".a",
+			pkgfullpath + ".o",
+		} {
+			if fi, err := os.Stat(filename); err == nil && !fi.IsDir() {
+				return filename
+			}
+		}
+	}
+	return ""
+}
@@ -5,6 +5,7 @@
 package irgen
 
 import (
+	"fmt"
 	"go/ast"
 	"go/token"
 	"golang.org/x/tools/go/loader"
@@ -17,11 +18,19 @@ import (
 // *importer.PackageInfo, and processes all of the
 // llgo source annotations attached to each top-level
 // function and global variable.
-func (c *compiler) processAnnotations(u *unit, pkginfo *loader.PackageInfo) {
+//
+// An error is returned if a name annotation would
+// give a definition the same symbol name as another
+// definition in the module.
+func (c *compiler) processAnnotations(u *unit, pkginfo *loader.PackageInfo) error {
 	members := make(map[types.Object]llvm.Value, len(u.globals))
 	for k, v := range u.globals {
 		members[k.(ssa.Member).Object()] = v
 	}
+	// named records the declaration whose name annotation
+	// claimed each symbol name.
+	named := make(map[string]*ast.Ident)
+	var err error
 	applyAttributes := func(attrs []Attribute, idents ...*ast.Ident) {
 		if len(attrs) == 0 {
 			return
@@ -29,6 +38,12 @@ func (c *compiler) processAnnotations(u *unit, pkginfo *loader.PackageInfo) {
 		for _, ident := range idents {
 			if v := members[pkginfo.ObjectOf(ident)]; !v.IsNil() {
 				for _, attr := range attrs {
+					if key, ok := attr.(unknownAttribute); ok {
+						c.warnf(ident.Pos(), "unknown attribute %q ignored", string(key))
+					}
+					if name, ok := attr.(nameAttribute); ok && err == nil {
+						err = c.checkNameCollision(u.pkg.Prog.Fset, v, string(name), ident, named)
+					}
 					attr.Apply(v)
 				}
 			}
@@ -52,4 +67,37 @@ func (c *compiler) processAnnotations(u *unit, pkginfo *loader.PackageInfo) {
 			}
 		}
 	}
+	return err
+}
+
+// isDefinition reports whether v is a function with a body,
+// or a global variable.
+func isDefinition(v llvm.Value) bool {
+	if !v.IsAFunction().IsNil() {
+		return v.BasicBlocksCount() != 0
+	}
+	return !v.IsAGlobalVariable().IsNil()
+}
+
+// checkNameCollision reports an error if renaming v, the value for the
+// declaration ident, to name would make it collide with another definition.
+// Declarations without definitions (such as //extern functions) may share
+// a name, and a definition may take the name of a declaration.
+func (c *compiler) checkNameCollision(fset *token.FileSet, v llvm.Value, name string, ident *ast.Ident, named map[string]*ast.Ident) error {
+	if !isDefinition(v) {
+		return nil
+	}
+	pos := fset.Position(ident.Pos())
+	if other, ok := named[name]; ok {
+		return fmt.Errorf("%s: %s: symbol %s is also defined by %s at %s", pos, ident.Name, name, other.Name, fset.Position(other.Pos()))
+	}
+	named[name] = ident
+	curr := c.module.NamedFunction(name)
+	if curr.IsNil() {
+		curr = c.module.NamedGlobal(name)
+	}
+	if !curr.IsNil() && curr != v && isDefinition(curr) {
+		return fmt.Errorf("%s: %s: symbol %s collides with %s", pos, ident.Name, name, DescribeSymbol(name))
+	}
+	return nil
 }
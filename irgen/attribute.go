@@ -7,7 +7,10 @@ package irgen
 import (
 	"fmt"
 	"go/ast"
+	"go/scanner"
+	"go/token"
 	"llvm.org/llvm/bindings/go/llvm"
+	"strconv"
 	"strings"
 )
 
@@ -69,13 +72,27 @@ func parseAttribute(line string) Attribute {
 		return parseLLVMAttribute(strings.TrimSpace(value))
 	case "thread_local":
 		return tlsAttribute{}
+	case "asm":
+		return parseAsmAttribute(value)
+	case "fastmath":
+		return fastMathAttribute{}
+	case "cfuncptr":
+		return cfuncptrAttribute{}
+	case "cstring":
+		return cstringAttribute{}
+	case "gostring":
+		return gostringAttribute{}
 	default:
-		// FIXME decide what to do here. return error? log warning?
-		panic("unknown attribute key: " + key)
+		return unknownAttribute(key)
 	}
-	return nil
 }
 
+// unknownAttribute is an attribute with an unrecognised key. It has no
+// effect, other than to cause a warning.
+type unknownAttribute string
+
+func (unknownAttribute) Apply(llvm.Value) {}
+
 type linkageAttribute llvm.Linkage
 
 func (a linkageAttribute) Apply(v llvm.Value) {
@@ -166,3 +183,89 @@ type tlsAttribute struct{}
 func (tlsAttribute) Apply(v llvm.Value) {
 	v.SetThreadLocal(true)
 }
+
+// fastMathAttribute permits floating point operations in a function to be
+// optimized in ways that do not conform to IEEE 754.
+type fastMathAttribute struct{}
+
+func (fastMathAttribute) Apply(v llvm.Value) {
+	if v.IsAFunction().IsNil() {
+		panic(fmt.Errorf("fastmath attribute applied to non-function %s", v.Name()))
+	}
+	v.AddTargetDependentFunctionAttr("unsafe-fp-math", "true")
+	v.AddTargetDependentFunctionAttr("no-infs-fp-math", "true")
+	v.AddTargetDependentFunctionAttr("no-nans-fp-math", "true")
+	v.AddTargetDependentFunctionAttr("less-precise-fpmad", "true")
+}
+
+// asmAttribute gives a body-less function a body consisting of a single
+// inline assembly expression. The function's parameters are passed as the
+// expression's operands, and its result (if any) is the function's result.
+//
+// For example:
+//
+//	// #llgo asm: "mfence", "~{memory}"
+//	func fence()
+type asmAttribute struct {
+	template, constraints string
+}
+
+// parseAsmAttribute parses the value of an asm attribute, which is an
+// assembly template optionally followed by a constraint string, each
+// written as a Go string literal.
+func parseAsmAttribute(value string) asmAttribute {
+	var strs []string
+	var s scanner.Scanner
+	src := []byte(value)
+	fset := token.NewFileSet()
+	s.Init(fset.AddFile("", fset.Base(), len(src)), src, nil, 0)
+loop:
+	for {
+		_, tok, lit := s.Scan()
+		switch tok {
+		case token.STRING:
+			str, err := strconv.Unquote(lit)
+			if err != nil {
+				panic(fmt.Errorf("invalid asm attribute %q: %v", value, err))
+			}
+			strs = append(strs, str)
+		case token.COMMA:
+		case token.SEMICOLON, token.EOF:
+			break loop
+		default:
+			panic(fmt.Errorf("invalid asm attribute %q", value))
+		}
+	}
+	switch len(strs) {
+	case 1:
+		return asmAttribute{template: strs[0]}
+	case 2:
+		return asmAttribute{template: strs[0], constraints: strs[1]}
+	default:
+		panic(fmt.Errorf("invalid asm attribute %q", value))
+	}
+}
+
+func (a asmAttribute) Apply(v llvm.Value) {
+	if v.IsAFunction().IsNil() {
+		panic(fmt.Errorf("asm attribute applied to non-function %s", v.Name()))
+	}
+	if v.BasicBlocksCount() != 0 {
+		panic(fmt.Errorf("asm attribute applied to function %s, which has a body", v.Name()))
+	}
+
+	ftyp := v.Type().ElementType()
+	asm := llvm.InlineAsm(ftyp, a.template, a.constraints, true, false)
+
+	ctx := ftyp.Context()
+	builder := ctx.NewBuilder()
+	defer builder.Dispose()
+	builder.SetInsertPointAtEnd(ctx.AddBasicBlock(v, "entry"))
+	result := builder.CreateCall(asm, v.Params(), "")
+	if ftyp.ReturnType().TypeKind() == llvm.VoidTypeKind {
+		builder.CreateRetVoid()
+	} else {
+		builder.CreateRet(result)
+	}
+	v.AddFunctionAttr(llvm.NoUnwindAttribute)
+}
@@ -0,0 +1,205 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package irgen
+
+import (
+	"fmt"
+	"go/ast"
+
+	"golang.org/x/tools/go/exact"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/types"
+	"llvm.org/llvm/bindings/go/llvm"
+)
+
+// bodylessFunc identifies a body-less function whose calls are replaced by
+// the compiler, rather than compiled as calls.
+type bodylessFunc int
+
+const (
+	// notBodylessFunc is any other function, which is called normally.
+	notBodylessFunc bodylessFunc = iota
+
+	// cfuncptrFunc is a function with the cfuncptr attribute.
+	cfuncptrFunc
+
+	// cstringFunc is a function with the cstring attribute.
+	cstringFunc
+
+	// gostringFunc is a function with the gostring attribute.
+	gostringFunc
+)
+
+// bodylessAttributeName maps each bodylessFunc to the name of its attribute.
+var bodylessAttributeName = [...]string{
+	cfuncptrFunc: "cfuncptr",
+	cstringFunc:  "cstring",
+	gostringFunc: "gostring",
+}
+
+// cgoBodylessFuncs maps the names of the body-less functions that
+// "go tool cgo -gccgo" declares for C.CString, C.GoString and C.GoStringN
+// to the attribute they are compiled as having, so that code using the
+// cgo subset of C interoperability needs neither cgo's C helpers nor the
+// attributes.
+var cgoBodylessFuncs = map[string]bodylessFunc{
+	"_Cfunc_CString":   cstringFunc,
+	"_Cfunc_GoString":  gostringFunc,
+	"_Cfunc_GoStringN": gostringFunc,
+}
+
+// applyBodylessAttribute checks that the attribute named name, which marks
+// a function whose calls are replaced by the compiler, is applied to a
+// function. That the function is declared without a body is checked by
+// bodylessFunc, as the compiler gives such functions a body of its own.
+func applyBodylessAttribute(name string, v llvm.Value) {
+	if v.IsAFunction().IsNil() {
+		panic(fmt.Errorf("%s attribute applied to non-function %s", name, v.Name()))
+	}
+}
+
+// bodylessFunc reports whether calls to f are replaced by the compiler,
+// and if so, how. The result is computed once for each function. Misuses
+// of the attributes are reported at the declaration of f, which is then
+// treated as an ordinary function.
+func (u *unit) bodylessFunc(f *ssa.Function) bodylessFunc {
+	kind, ok := u.bodylessFuncs[f]
+	if !ok {
+		var err string
+		kind, err = classifyBodylessFunc(f)
+		if err != "" {
+			u.errors.Add(f.Prog.Fset.Position(f.Pos()), err)
+			kind = notBodylessFunc
+		}
+		u.bodylessFuncs[f] = kind
+	}
+	return kind
+}
+
+// classifyBodylessFunc returns the kind of f from its name, if it is one
+// of cgo's helpers, or else from the attributes in its doc comment. If f
+// misuses an attribute, the kind is returned with a description of the
+// error.
+func classifyBodylessFunc(f *ssa.Function) (bodylessFunc, string) {
+	if f.Signature.Recv() != nil {
+		return notBodylessFunc, ""
+	}
+	if kind, ok := cgoBodylessFuncs[f.Name()]; ok && f.Blocks == nil {
+		return kind, checkBodylessSignature(f, kind)
+	}
+	decl, ok := f.Syntax().(*ast.FuncDecl)
+	if !ok {
+		return notBodylessFunc, ""
+	}
+	for _, attr := range parseAttributes(decl.Doc) {
+		var kind bodylessFunc
+		switch attr.(type) {
+		case cfuncptrAttribute:
+			kind = cfuncptrFunc
+		case cstringAttribute:
+			kind = cstringFunc
+		case gostringAttribute:
+			kind = gostringFunc
+		default:
+			continue
+		}
+		if f.Blocks != nil {
+			return kind, fmt.Sprintf("%s attribute applied to function %s, which has a body", bodylessAttributeName[kind], f.Name())
+		}
+		return kind, checkBodylessSignature(f, kind)
+	}
+	return notBodylessFunc, ""
+}
+
+// checkBodylessSignature checks that the signature of f is appropriate for
+// a body-less function of the given kind, returning a description of the
+// error if it is not.
+func checkBodylessSignature(f *ssa.Function, kind bodylessFunc) string {
+	sig := f.Signature
+	params, results := sig.Params(), sig.Results()
+	var ok bool
+	var want string
+	switch kind {
+	case cfuncptrFunc:
+		ok = params.Len() == 1 && results.Len() == 1 &&
+			isSignature(params.At(0).Type()) && isPointerType(results.At(0).Type())
+		want = "a parameter of func type and a pointer result"
+	case cstringFunc:
+		ok = params.Len() == 1 && results.Len() == 1 &&
+			isString(params.At(0).Type()) && isPointerType(results.At(0).Type())
+		want = "a string parameter and a pointer result"
+	case gostringFunc:
+		ok = (params.Len() == 1 || params.Len() == 2 && isInteger(params.At(1).Type())) &&
+			results.Len() == 1 && isPointerType(params.At(0).Type()) && isString(results.At(0).Type())
+		want = "a pointer parameter, an optional integer length parameter, and a string result"
+	}
+	if ok {
+		return ""
+	}
+	return fmt.Sprintf("%s function %s must have %s", bodylessAttributeName[kind], f.Name(), want)
+}
+
+// defineBodylessFunc gives the body-less function f, declared with one of
+// the attributes, a body, for the calls that the compiler cannot replace:
+// calls through func values, and calls from other packages, as attributes
+// are not recorded in export data. Functions with the cstring and gostring
+// attributes perform their conversion; those with the cfuncptr attribute,
+// whose argument can only be checked at compile time, panic.
+func (u *unit) defineBodylessFunc(f *ssa.Function, llfn llvm.Value, kind bodylessFunc) {
+	fr := newFrame(u, llfn)
+	defer fr.dispose()
+	fr.addCommonFunctionAttrs(llfn)
+	llfn.SetLinkage(u.getFunctionLinkage(f))
+	delete(u.undefinedFuncs, f)
+
+	fti := u.llvmtypes.getSignatureInfo(f.Signature)
+	fr.retInf = fti.retInf
+	entry := u.llvmtypes.ctx.AddBasicBlock(llfn, "entry")
+	fr.builder.SetInsertPointAtEnd(entry)
+	args := make([]*govalue, len(f.Params))
+	for i, param := range f.Params {
+		args[i] = newValue(fti.argInfos[i].decode(u.llvmtypes.ctx, fr.builder, fr.builder), param.Type())
+	}
+	body := u.llvmtypes.ctx.AddBasicBlock(llfn, "body")
+	fr.allocaBuilder.SetInsertPointBefore(fr.builder.CreateBr(body))
+	fr.builder.SetInsertPointAtEnd(body)
+
+	result := f.Signature.Results().At(0).Type()
+	switch kind {
+	case cfuncptrFunc:
+		msg := fmt.Sprintf("cfuncptr function %s.%s may only be called directly, from its own package", f.Pkg.Object.Path(), f.Name())
+		str := fr.newValueFromConst(exact.MakeString(msg), types.Typ[types.String])
+		fr.callPanic(fr.makeInterface(str.value, str.Type(), types.NewInterface(nil, nil)))
+		return
+	case cstringFunc:
+		args[0] = fr.cstring(args[0], result)
+	case gostringFunc:
+		var n *govalue
+		if len(args) == 2 {
+			n = args[1]
+		}
+		args[0] = fr.gostring(args[0], n)
+	}
+	fr.retInf.encode(u.llvmtypes.ctx, fr.allocaBuilder, fr.builder, []llvm.Value{args[0].value})
+}
+
+// callBodylessFunc compiles a call to the body-less function f, returning
+// its results.
+func (fr *frame) callBodylessFunc(f *ssa.Function, kind bodylessFunc, call *ssa.CallCommon, args []*govalue) []*govalue {
+	result := f.Signature.Results().At(0).Type()
+	switch kind {
+	case cfuncptrFunc:
+		return []*govalue{fr.cfuncptr(call.Args[0], result)}
+	case cstringFunc:
+		return []*govalue{fr.cstring(args[0], result)}
+	case gostringFunc:
+		var n *govalue
+		if len(args) == 2 {
+			n = args[1]
+		}
+		return []*govalue{fr.gostring(args[0], n)}
+	}
+	panic("unreachable")
+}
@@ -53,7 +53,7 @@ func (fr *frame) callAppend(a, b *govalue) *govalue {
 	bptr := fr.builder.CreateExtractValue(b.value, 0, "")
 	blen := fr.builder.CreateExtractValue(b.value, 1, "")
 	elemsizeInt64 := fr.types.Sizeof(a.Type().Underlying().(*types.Slice).Elem())
-	elemsize := llvm.ConstInt(fr.target.IntPtrType(), uint64(elemsizeInt64), false)
+	elemsize := llvm.ConstInt(fr.llvmtypes.inttype, uint64(elemsizeInt64), false)
 	result := fr.runtime.append.call(fr, a.value, bptr, blen, elemsize)[0]
 	return newValue(result, a.Type())
 }
@@ -76,9 +76,9 @@ func (fr *frame) callCopy(dest, source *govalue) *govalue {
 
 func (fr *frame) callRecover(isDeferredRecover bool) *govalue {
 	startbb := fr.builder.GetInsertBlock()
-	recoverbb := llvm.AddBasicBlock(fr.function, "")
-	contbb := llvm.AddBasicBlock(fr.function, "")
-	canRecover := fr.builder.CreateTrunc(fr.canRecover, llvm.Int1Type(), "")
+	recoverbb := fr.llvmtypes.ctx.AddBasicBlock(fr.function, "")
+	contbb := fr.llvmtypes.ctx.AddBasicBlock(fr.function, "")
+	canRecover := fr.builder.CreateTrunc(fr.canRecover, fr.llvmtypes.ctx.Int1Type(), "")
 	fr.builder.CreateCondBr(canRecover, recoverbb, contbb)
 
 	fr.builder.SetInsertPointAtEnd(recoverbb)
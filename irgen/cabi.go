@@ -301,7 +301,7 @@ func directEncode(ctx llvm.Context, allocaBuilder llvm.Builder, builder llvm.Bui
 		args[0] = builder.CreateLoad(bitcast, "")
 
 	case 2:
-		encodeType := llvm.StructType(argTypes, false)
+		encodeType := ctx.StructType(argTypes, false)
 		alloca := allocaBuilder.CreateAlloca(valType, "")
 		bitcast := builder.CreateBitCast(alloca, llvm.PointerType(encodeType, 0), "")
 		builder.CreateStore(val, alloca)
@@ -534,7 +534,7 @@ func (tm *llvmTypeMap) getFunctionTypeInfo(args []types.Type, results []types.Ty
 	var returnType llvm.Type
 	var argTypes []llvm.Type
 	if len(results) == 0 {
-		returnType = llvm.VoidType()
+		returnType = tm.ctx.VoidType()
 		fi.retInf = &directRetInfo{}
 	} else {
 		aik := tm.classify(results...)
@@ -561,19 +561,19 @@ func (tm *llvmTypeMap) getFunctionTypeInfo(args []types.Type, results []types.Ty
 			retTypes, retAttrs, _, _ := tm.expandType(nil, nil, bt)
 			switch len(retTypes) {
 			case 0: // e.g., empty struct
-				returnType = llvm.VoidType()
+				returnType = tm.ctx.VoidType()
 			case 1:
 				returnType = retTypes[0]
 				fi.retAttr = retAttrs[0]
 			case 2:
-				returnType = llvm.StructType(retTypes, false)
+				returnType = tm.ctx.StructType(retTypes, false)
 			default:
 				panic("unexpected expandType result")
 			}
 			fi.retInf = &directRetInfo{numResults: len(results), retTypes: retTypes, resultsType: resultsType}
 
 		case AIK_Indirect:
-			returnType = llvm.VoidType()
+			returnType = tm.ctx.VoidType()
 			argTypes = []llvm.Type{llvm.PointerType(resultsType, 0)}
 			fi.argAttrs = []llvm.Attribute{llvm.StructRetAttribute}
 			fi.retInf = &indirectRetInfo{numResults: len(results), resultsType: resultsType}
@@ -23,7 +23,7 @@ func (fr *frame) createCall(fn *govalue, argValues []*govalue) []*govalue {
 	if fr.unwindBlock.IsNil() {
 		results = typinfo.call(fr.types.ctx, fr.allocaBuilder, fr.builder, fn.value, args)
 	} else {
-		contbb := llvm.AddBasicBlock(fr.function, "")
+		contbb := fr.llvmtypes.ctx.AddBasicBlock(fr.function, "")
 		results = typinfo.invoke(fr.types.ctx, fr.allocaBuilder, fr.builder, fn.value, args, contbb, fr.unwindBlock)
 	}
 
@@ -0,0 +1,48 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package irgen
+
+import (
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/types"
+	"llvm.org/llvm/bindings/go/llvm"
+)
+
+// cfuncptrAttribute marks a body-less function as converting a Go func
+// value to a raw code pointer, which may be passed to C as a function
+// pointer. For example:
+//
+//	// #llgo cfuncptr
+//	func callbackPtr(f func(unsafe.Pointer) int32) unsafe.Pointer
+//
+// Calls to such a function are replaced by the code pointer of the
+// argument. The argument must be a function, or a function literal, that
+// captures no variables; anything else (closures, bound methods, func
+// values loaded from variables) is rejected at compile time, as C would
+// have no way to supply the captured context.
+//
+// Only direct calls from the declaring package can be checked, as
+// attributes are not recorded in export data: a call through a func value,
+// or from another package, panics.
+//
+// Go functions use the C calling convention for their parameters and
+// results, so the resulting pointer may be called from C as a function
+// with the corresponding C signature.
+type cfuncptrAttribute struct{}
+
+func (cfuncptrAttribute) Apply(v llvm.Value) {
+	applyBodylessAttribute("cfuncptr", v)
+}
+
+// cfuncptr returns the code pointer of the capture-free function fn,
+// converted to type typ.
+func (fr *frame) cfuncptr(fn ssa.Value, typ types.Type) *govalue {
+	f, ok := fn.(*ssa.Function)
+	if !ok || len(f.FreeVars) != 0 {
+		fr.errorf(fr.pos, "cannot convert %s to a C function pointer: only functions without captured variables may be converted", fn.Name())
+	}
+	llfn := fr.resolveFunctionGlobal(f)
+	return newValue(llvm.ConstBitCast(llfn, fr.llvmtypes.ToLLVM(typ)), typ)
+}
@@ -24,7 +24,7 @@ func (fr *frame) chanSend(ch *govalue, elem *govalue) {
 	elem = fr.convert(elem, elemtyp)
 	elemptr := fr.allocaBuilder.CreateAlloca(elem.value.Type(), "")
 	fr.builder.CreateStore(elem.value, elemptr)
-	elemptr = fr.builder.CreateBitCast(elemptr, llvm.PointerType(llvm.Int8Type(), 0), "")
+	elemptr = fr.builder.CreateBitCast(elemptr, llvm.PointerType(fr.llvmtypes.ctx.Int8Type(), 0), "")
 	chantyp := fr.types.ToRuntime(ch.Type())
 	fr.runtime.sendBig.call(fr, chantyp, ch.value, elemptr)
 }
@@ -33,7 +33,7 @@ func (fr *frame) chanSend(ch *govalue, elem *govalue) {
 func (fr *frame) chanRecv(ch *govalue, commaOk bool) (x, ok *govalue) {
 	elemtyp := ch.Type().Underlying().(*types.Chan).Elem()
 	ptr := fr.allocaBuilder.CreateAlloca(fr.types.ToLLVM(elemtyp), "")
-	ptri8 := fr.builder.CreateBitCast(ptr, llvm.PointerType(llvm.Int8Type(), 0), "")
+	ptri8 := fr.builder.CreateBitCast(ptr, llvm.PointerType(fr.llvmtypes.ctx.Int8Type(), 0), "")
 	chantyp := fr.types.ToRuntime(ch.Type())
 
 	if commaOk {
@@ -64,7 +64,7 @@ func (fr *frame) chanSelect(states []selectState, blocking bool) (index, recvOk
 		// non-blocking means there's a default case
 		n++
 	}
-	size := llvm.ConstInt(llvm.Int32Type(), n, false)
+	size := llvm.ConstInt(fr.llvmtypes.ctx.Int32Type(), n, false)
 	selectp := fr.runtime.newSelect.call(fr, size)[0]
 
 	// Allocate stack for the values to send and receive.
@@ -90,11 +90,11 @@ func (fr *frame) chanSelect(states []selectState, blocking bool) (index, recvOk
 	}
 	if !blocking {
 		// If the default case is chosen, the index must be -1.
-		fr.runtime.selectdefault.call(fr, selectp, llvm.ConstAllOnes(llvm.Int32Type()))
+		fr.runtime.selectdefault.call(fr, selectp, llvm.ConstAllOnes(fr.llvmtypes.ctx.Int32Type()))
 	}
 	for i, state := range states {
 		ch := state.Chan.value
-		index := llvm.ConstInt(llvm.Int32Type(), uint64(i), false)
+		index := llvm.ConstInt(fr.llvmtypes.ctx.Int32Type(), uint64(i), false)
 		if state.Dir == types.SendOnly {
 			fr.runtime.selectsend.call(fr, selectp, ch, ptrs[i], index)
 		} else {
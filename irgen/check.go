@@ -0,0 +1,181 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package irgen
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"io/ioutil"
+
+	"golang.org/x/tools/go/gccgoimporter"
+	"golang.org/x/tools/go/types"
+	"llvm.org/llvm/bindings/go/llvm"
+)
+
+// A Checker parses and type checks packages without generating code, for
+// clients such as editors that need diagnostics soon after each change.
+//
+// A Checker retains state between calls to Check: files whose contents
+// have not changed since they were last checked are not parsed again, and
+// imported packages are read from export data only once, until
+// InvalidateImports is called. The package itself is always type checked
+// in full, as a change to one file may introduce or fix errors in any
+// other.
+type Checker struct {
+	ctx       llvm.Context
+	llvmtypes *llvmTypeMap
+	fset      *token.FileSet
+	files     map[string]*checkedFile
+	importer  types.Importer
+	imports   map[string]*types.Package
+	disposed  bool
+
+	// stale is the total size of the files in fset that are no longer
+	// retained. A token.FileSet cannot forget a file, so once stale
+	// exceeds the size of the retained files, Check starts a new
+	// generation: a new FileSet, into which every file is parsed again.
+	stale int
+}
+
+// A checkedFile is a file parsed by a previous call to Check.
+type checkedFile struct {
+	src  []byte
+	file *ast.File
+	err  error
+}
+
+// NewChecker returns a Checker for the target and import paths of c.
+// The caller must call the Checker's Dispose method when done with it.
+func (c *Compiler) NewChecker() (*Checker, error) {
+	initmap := make(map[*types.Package]gccgoimporter.InitData)
+	importer, err := newImporter(&c.opts, initmap)
+	if err != nil {
+		return nil, err
+	}
+	ctx := llvm.NewContext()
+	return &Checker{
+		ctx:       ctx,
+		llvmtypes: NewLLVMTypeMap(ctx, c.target),
+		fset:      token.NewFileSet(),
+		files:     make(map[string]*checkedFile),
+		importer:  importer,
+		imports:   make(map[string]*types.Package),
+	}, nil
+}
+
+// InvalidateImports discards the imported packages read by previous calls
+// to Check, so that the next call reads their export data again; for
+// example, after a dependency has been rebuilt. As an imported package
+// refers to the packages it imports, the whole cache is discarded.
+func (ch *Checker) InvalidateImports() {
+	ch.imports = make(map[string]*types.Package)
+}
+
+// Dispose releases the native resources held by the Checker.
+func (ch *Checker) Dispose() {
+	if ch.disposed {
+		return
+	}
+	ch.ctx.Dispose()
+	ch.disposed = true
+}
+
+// Check parses and type checks the given files as the package with the
+// given import path, and returns the errors found, if any, as a
+// scanner.ErrorList. The contents of a file are taken from overlay if
+// present there (for example, an unsaved editor buffer), and otherwise
+// read from disk.
+func (ch *Checker) Check(filenames []string, importpath string, overlay map[string][]byte) error {
+	live := 0
+	for _, cf := range ch.files {
+		live += len(cf.src)
+	}
+	if ch.stale > live {
+		ch.fset = token.NewFileSet()
+		ch.files = make(map[string]*checkedFile)
+		ch.stale = 0
+	}
+
+	var errors scanner.ErrorList
+	files := make([]*ast.File, 0, len(filenames))
+	retained := make(map[string]*checkedFile, len(filenames))
+	for _, filename := range filenames {
+		src, ok := overlay[filename]
+		if !ok {
+			var err error
+			src, err = ioutil.ReadFile(filename)
+			if err != nil {
+				errors.Add(token.Position{Filename: filename}, err.Error())
+				continue
+			}
+		}
+		cf := ch.files[filename]
+		if cf == nil || !bytes.Equal(cf.src, src) {
+			mode := parser.DeclarationErrors | parser.ParseComments
+			file, err := parser.ParseFile(ch.fset, filename, src, mode)
+			cf = &checkedFile{src: src, file: file, err: err}
+		}
+		retained[filename] = cf
+		if list, ok := cf.err.(scanner.ErrorList); ok {
+			errors = append(errors, list...)
+		} else if cf.err != nil {
+			errors.Add(token.Position{Filename: filename}, cf.err.Error())
+		}
+		files = append(files, cf.file)
+	}
+	// Forget files that are no longer part of the package, or that have
+	// been parsed again.
+	for filename, cf := range ch.files {
+		if retained[filename] != cf {
+			ch.stale += len(cf.src)
+		}
+	}
+	ch.files = retained
+
+	// As when compiling, do not type check files with syntax errors.
+	if len(errors) != 0 || len(files) == 0 {
+		errors.Sort()
+		return errors.Err()
+	}
+	if importpath == "" {
+		importpath = files[0].Name.String()
+	}
+	if err := checkImportCycles(ch.fset, files, importpath); err != nil {
+		errors.Add(token.Position{}, err.Error())
+		return errors.Err()
+	}
+
+	config := types.Config{
+		Import: func(_ map[string]*types.Package, path string) (*types.Package, error) {
+			return ch.importer(ch.imports, path)
+		},
+		Sizes: ch.llvmtypes,
+		Error: func(err error) {
+			if terr, ok := err.(types.Error); ok {
+				errors.Add(terr.Fset.Position(terr.Pos), terr.Msg)
+			} else {
+				errors.Add(token.Position{}, err.Error())
+			}
+		},
+	}
+	info := &types.Info{
+		Types: make(map[ast.Expr]types.TypeAndValue),
+		Uses:  make(map[*ast.Ident]types.Object),
+	}
+	pkg, _ := config.Check(importpath, ch.fset, files, info)
+	if pkg != nil && len(errors) == 0 {
+		if err := checkImportedCycles(pkg); err != nil {
+			errors.Add(token.Position{}, err.Error())
+		}
+	}
+	if len(errors) == 0 {
+		errors = checkSwitches(ch.fset, files, info)
+	}
+	errors.Sort()
+	return errors.Err()
+}
@@ -7,11 +7,15 @@ package irgen
 import (
 	"bytes"
 	"fmt"
+	"go/scanner"
 	"go/token"
+	"io/ioutil"
 	"log"
+	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	llgobuild "github.com/go-llvm/llgo/build"
 	"github.com/go-llvm/llgo/debug"
@@ -24,11 +28,20 @@ import (
 	"golang.org/x/tools/go/types"
 )
 
+// Module is the result of compiling a package. Each Module has its own
+// LLVM context, so that compilations do not share (and accumulate) native
+// state; both are released by Dispose.
+//
+// ExportData holds the export data of a package other than main, and
+// InitGraph the encoded initialization graph of the packages compiled into
+// the module (see LinkTimeInit).
 type Module struct {
 	llvm.Module
 	Path       string
 	ExportData []byte
+	InitGraph  []byte
 	disposed   bool
+	ctx        llvm.Context
 }
 
 func (m *Module) Dispose() {
@@ -36,6 +49,7 @@ func (m *Module) Dispose() {
 		return
 	}
 	m.Module.Dispose()
+	m.ctx.Dispose()
 	m.disposed = true
 }
 
@@ -71,12 +85,101 @@ type CompilerOptions struct {
 	// SanitizerAttribute is an attribute to apply to functions to enable
 	// dynamic instrumentation using a sanitizer.
 	SanitizerAttribute llvm.Attribute
+
+	// DisableFramePointerElim decides whether functions retain the frame
+	// pointer, allowing profilers and debuggers to unwind the stack
+	// without debug info.
+	DisableFramePointerElim bool
+
+	// UnwindTables decides whether unwind tables are emitted for every
+	// function, rather than only those that may be unwound through by
+	// an exception.
+	UnwindTables bool
+
+	// FastMath decides whether floating point operations may be optimized
+	// in ways that do not conform to IEEE 754 (for example, by assuming
+	// that NaNs and infinities do not occur). This is off by default, as
+	// it changes the semantics of Go programs.
+	FastMath bool
+
+	// SoftFloat decides whether floating point operations are lowered to
+	// calls to software floating point routines (as provided by libgcc or
+	// compiler-rt), for targets without a floating point unit.
+	SoftFloat bool
+
+	// TrapOverflow decides whether to generate code that panics on signed
+	// integer overflow, and on lossy truncations in compiler-generated
+	// integer conversions. Go defines integer arithmetic to wrap, so this
+	// is only intended as a debugging aid.
+	TrapOverflow bool
+
+	// Warnings, if non-nil, is called with each non-fatal diagnostic
+	// produced during compilation, in the order they are produced.
+	Warnings func(Warning)
+
+	// TimePhase, if non-nil, is called with the name and duration of each
+	// phase of compilation (such as "parse" or "typecheck") as it
+	// completes. Code generation is reported once for each source file,
+	// as "codegen" followed by the file name, and once more as "codegen"
+	// for the time not attributable to any file.
+	TimePhase func(phase string, d time.Duration)
+
+	// PartialIRFile, if non-empty, is the name of a file to which the
+	// partially built module is written as textual IR if compilation
+	// fails (or panics) after code generation has begun. The partial body
+	// of each function whose code generation failed is kept in the module,
+	// with PartialIRSuffix appended to its name.
+	PartialIRFile string
+
+	// LinkTimeInit decides whether the __go_init_main function of a program
+	// is left to be defined when linking, by a module returned by
+	// CompileInitMain, rather than when compiling package main. This allows
+	// packages to be recompiled without recompiling the packages that
+	// import them, as the order in which init functions are called is then
+	// determined from the initialization graphs of the linked objects.
+	LinkTimeInit bool
+
+	// RuntimeHooks decides whether generated code calls the runtime's
+	// allocator, goroutine creation and panic functions through weak hook
+	// functions, which embedders may replace at link time.
+	RuntimeHooks bool
+
+	// StackRoots decides whether each value that may contain pointers is
+	// also stored to a stack slot, where libgo's conservative stack scan
+	// can find it, even if optimizations keep the value in a register.
+	StackRoots bool
+
+	// Vet decides whether to warn about code that compiles, but is known
+	// to misbehave under llgo's runtime. It is off by default, so that
+	// packages (such as those of the standard library) that knowingly
+	// depend on libgo's representations are compiled without warnings.
+	Vet bool
+}
+
+// PartialIRSuffix is appended to the names of functions whose code
+// generation failed, in partial IR written to CompilerOptions.PartialIRFile.
+const PartialIRSuffix = ".llgo.failed"
+
+// A Warning is a non-fatal diagnostic produced during compilation, such
+// as an ignored attribute or an unexpectedly expensive construct.
+type Warning struct {
+	Pos token.Position
+	Msg string
+}
+
+func (w Warning) String() string {
+	if w.Pos.IsValid() {
+		return w.Pos.String() + ": warning: " + w.Msg
+	}
+	return "warning: " + w.Msg
 }
 
 type Compiler struct {
 	opts       CompilerOptions
 	dataLayout string
+	target     llvm.TargetData
 	pnacl      bool
+	disposed   bool
 }
 
 func NewCompiler(opts CompilerOptions) (*Compiler, error) {
@@ -90,19 +193,78 @@ func NewCompiler(opts CompilerOptions) (*Compiler, error) {
 		return nil, err
 	}
 	compiler.dataLayout = dataLayout
+	compiler.target = llvm.NewTargetData(dataLayout)
 	return compiler, nil
 }
 
+// Dispose releases the native resources held by the Compiler. Modules
+// returned by Compile are unaffected, and must be disposed separately.
+func (c *Compiler) Dispose() {
+	if c.disposed {
+		return
+	}
+	c.target.Dispose()
+	c.disposed = true
+}
+
+// Compile compiles the given files as the package with the given import
+// path. The caller owns the resulting Module, and must call its Dispose
+// method when done with it.
 func (c *Compiler) Compile(filenames []string, importpath string) (m *Module, err error) {
-	target := llvm.NewTargetData(c.dataLayout)
-	compiler := &compiler{
+	return c.compile(filenames, importpath, false)
+}
+
+// CompileProgram compiles the given files as package main, together with
+// each package they transitively import that is found in source form
+// outside GOROOT, into a single Module. Packages in GOROOT are imported
+// from export data as usual. As the Module contains the whole program
+// apart from those packages, the definitions of the imported source
+// packages are given internal linkage, so that the optimizer may inline,
+// specialize or remove them. The caller owns the resulting Module, and
+// must call its Dispose method when done with it.
+func (c *Compiler) CompileProgram(filenames []string) (m *Module, err error) {
+	return c.compile(filenames, "main", true)
+}
+
+// newCompiler returns a compiler with the options of c, that creates LLVM
+// types in ctx.
+func (c *Compiler) newCompiler(ctx llvm.Context) *compiler {
+	return &compiler{
 		CompilerOptions: c.opts,
 		dataLayout:      c.dataLayout,
-		target:          target,
+		target:          c.target,
 		pnacl:           c.pnacl,
-		llvmtypes:       NewLLVMTypeMap(llvm.GlobalContext(), target),
+		llvmtypes:       NewLLVMTypeMap(ctx, c.target),
 	}
-	return compiler.compile(filenames, importpath)
+}
+
+func (c *Compiler) compile(filenames []string, importpath string, wholeProgram bool) (m *Module, err error) {
+	ctx := llvm.NewContext()
+	compiler := c.newCompiler(ctx)
+	compiler.wholeProgram = wholeProgram
+	if c.opts.PartialIRFile != "" {
+		defer func() {
+			if r := recover(); r != nil {
+				if compiler.module != nil {
+					compiler.writePartialIR(fmt.Sprintf("panic: %v", r))
+				}
+				panic(r)
+			}
+		}()
+	}
+	m, err = compiler.compile(filenames, importpath)
+	if err != nil {
+		if compiler.module != nil {
+			if c.opts.PartialIRFile != "" {
+				compiler.writePartialIR(err.Error())
+			}
+			compiler.module.Module.Dispose()
+		}
+		ctx.Dispose()
+		return nil, err
+	}
+	m.ctx = ctx
+	return m, nil
 }
 
 type compiler struct {
@@ -127,6 +289,13 @@ type compiler struct {
 	// compile PNaCl modules.
 	pnacl bool
 
+	// wholeProgram is set to true if the imported packages found in
+	// source form are compiled into the same module as the main package.
+	wholeProgram bool
+
+	// unit is the translation unit of the package being compiled.
+	unit *unit
+
 	debug *debug.DIBuilder
 }
 
@@ -136,36 +305,69 @@ func (c *compiler) logf(format string, v ...interface{}) {
 	}
 }
 
+func (c *compiler) warnf(pos token.Pos, format string, v ...interface{}) {
+	if c.Warnings != nil {
+		var position token.Position
+		if pos.IsValid() {
+			position = c.fileset.Position(pos)
+		}
+		c.Warnings(Warning{position, fmt.Sprintf(format, v...)})
+	}
+}
+
 func (c *compiler) addCommonFunctionAttrs(fn llvm.Value) {
 	fn.AddTargetDependentFunctionAttr("disable-tail-calls", "true")
 	fn.AddTargetDependentFunctionAttr("split-stack", "")
 	if attr := c.SanitizerAttribute; attr != 0 {
 		fn.AddFunctionAttr(attr)
 	}
+	if c.DisableFramePointerElim {
+		fn.AddTargetDependentFunctionAttr("no-frame-pointer-elim", "true")
+	}
+	if c.UnwindTables {
+		fn.AddFunctionAttr(llvm.UWTableAttribute)
+	}
+	if c.FastMath {
+		fastMathAttribute{}.Apply(fn)
+	}
+	if c.SoftFloat {
+		fn.AddTargetDependentFunctionAttr("use-soft-float", "true")
+	}
+}
+
+// newImporter returns an importer that reads export data from the paths
+// given by opts, recording the initialization data of each imported
+// package in initmap. Export data whose checksum does not match its
+// contents is rejected.
+func newImporter(opts *CompilerOptions, initmap map[*types.Package]gccgoimporter.InitData) (types.Importer, error) {
+	if opts.GccgoPath == "" {
+		paths := append(append([]string{}, opts.ImportPaths...), ".")
+		return checksumImporter(gccgoimporter.GetImporter(paths, initmap), paths), nil
+	}
+	var inst gccgoimporter.GccgoInstallation
+	if err := inst.InitFromDriver(opts.GccgoPath); err != nil {
+		return nil, err
+	}
+	paths := append(append([]string{}, opts.ImportPaths...), inst.SearchPaths()...)
+	return checksumImporter(inst.GetImporter(opts.ImportPaths, initmap), paths), nil
 }
 
 func (compiler *compiler) compile(filenames []string, importpath string) (m *Module, err error) {
+	start := time.Now()
 	buildctx, err := llgobuild.ContextFromTriple(compiler.TargetTriple)
 	if err != nil {
 		return nil, err
 	}
 
 	initmap := make(map[*types.Package]gccgoimporter.InitData)
-	var importer types.Importer
-	if compiler.GccgoPath == "" {
-		paths := append(append([]string{}, compiler.ImportPaths...), ".")
-		importer = gccgoimporter.GetImporter(paths, initmap)
-	} else {
-		var inst gccgoimporter.GccgoInstallation
-		err = inst.InitFromDriver(compiler.GccgoPath)
-		if err != nil {
-			return nil, err
-		}
-		importer = inst.GetImporter(compiler.ImportPaths, initmap)
+	importer, err := newImporter(&compiler.CompilerOptions, initmap)
+	if err != nil {
+		return nil, err
 	}
 
+	compiler.fileset = token.NewFileSet()
 	impcfg := &loader.Config{
-		Fset: token.NewFileSet(),
+		Fset: compiler.fileset,
 		TypeChecker: types.Config{
 			Import: importer,
 			Sizes:  compiler.llvmtypes,
@@ -178,36 +380,84 @@ func (compiler *compiler) compile(filenames []string, importpath string) (m *Mod
 	if err != nil {
 		return nil, err
 	}
+	start = compiler.timePhase("parse", start)
 	// If no import path is specified, then set the import
 	// path to be the same as the package's name.
 	if importpath == "" {
 		importpath = astFiles[0].Name.String()
 	}
+	if err := checkImportCycles(impcfg.Fset, astFiles, importpath); err != nil {
+		return nil, err
+	}
 	impcfg.CreateFromFiles(importpath, astFiles...)
+	var srcImports []string
+	if compiler.wholeProgram {
+		srcImports = sourceImports(&buildctx.Context, astFiles, filepath.Dir(filenames[0]))
+		for _, path := range srcImports {
+			impcfg.Import(path)
+		}
+	}
 	iprog, err := impcfg.Load()
 	if err != nil {
 		return nil, err
 	}
+	if err := checkImportedCycles(iprog.InitialPackages()[0].Pkg); err != nil {
+		return nil, err
+	}
+	var switchErrors scanner.ErrorList
+	for _, pkginfo := range iprog.AllPackages {
+		switchErrors = append(switchErrors, checkSwitches(impcfg.Fset, pkginfo.Files, &pkginfo.Info)...)
+	}
+	switchErrors.Sort()
+	if err := switchErrors.Err(); err != nil {
+		return nil, err
+	}
+	start = compiler.timePhase("typecheck", start)
+
 	program := ssa.Create(iprog, ssa.BareInits)
 	mainPkginfo := iprog.InitialPackages()[0]
 	mainPkg := program.CreatePackage(mainPkginfo)
 
+	// Create the packages imported from source, in dependency order.
+	pkginfos := make([]*loader.PackageInfo, 0, len(srcImports)+1)
+	pkgs := make([]*ssa.Package, 0, len(srcImports)+1)
+	for _, path := range srcImports {
+		pkginfo := iprog.Imported[path]
+		pkginfos = append(pkginfos, pkginfo)
+		pkgs = append(pkgs, program.CreatePackage(pkginfo))
+	}
+	pkginfos = append(pkginfos, mainPkginfo)
+	pkgs = append(pkgs, mainPkg)
+	if compiler.Vet {
+		for _, pkginfo := range pkginfos {
+			compiler.vetPackage(pkginfo)
+		}
+	}
+
 	// Create a Module, which contains the LLVM module.
 	modulename := importpath
-	compiler.module = &Module{Module: llvm.NewModule(modulename), Path: modulename}
+	compiler.module = &Module{Module: compiler.llvmtypes.ctx.NewModule(modulename), Path: modulename}
 	compiler.module.SetTarget(compiler.TargetTriple)
 	compiler.module.SetDataLayout(compiler.dataLayout)
 
 	// Create a new translation unit.
 	unit := newUnit(compiler, mainPkg)
+	unit.pkgs = pkgs
+	compiler.unit = unit
 
 	// Create the runtime interface.
 	compiler.runtime, err = newRuntimeInterface(compiler.module.Module, compiler.llvmtypes)
 	if err != nil {
 		return nil, err
 	}
+	if compiler.RuntimeHooks {
+		compiler.installRuntimeHooks(importpath == "main")
+	}
 
-	mainPkg.Build()
+	for _, pkg := range pkgs {
+		pkg.Build()
+	}
+	start = compiler.timePhase("ssa", start)
 
 	// Create a struct responsible for mapping static types to LLVM types,
 	// and to runtime/dynamic type values.
@@ -218,6 +468,12 @@ func (compiler *compiler) compile(filenames []string, importpath string) (m *Mod
 		compiler.runtime,
 		MethodResolver(unit),
 	)
+	for _, pkg := range pkgs {
+		compiler.types.addPackage(pkg.Object.Path())
+	}
+	if err := compiler.checkMangledPathCollisions(mainPkg.Object); err != nil {
+		return nil, err
+	}
 
 	if compiler.GenerateDebug {
 		compiler.debug = debug.NewDIBuilder(
@@ -230,20 +486,80 @@ func (compiler *compiler) compile(filenames []string, importpath string) (m *Mod
 		defer compiler.debug.Finalize()
 	}
 
-	unit.translatePackage(mainPkg)
-	compiler.processAnnotations(unit, mainPkginfo)
+	unit.translatePackages()
+	if len(unit.errors) != 0 {
+		unit.errors.Sort()
+		return nil, unit.errors
+	}
+	for _, pkginfo := range pkginfos {
+		if err := compiler.processAnnotations(unit, pkginfo); err != nil {
+			return nil, err
+		}
+	}
+	compiler.emitABISymbols(pkgs)
+	if compiler.wholeProgram {
+		compiler.internalizePackages(pkgs[:len(pkgs)-1])
+	}
 
-	if importpath == "main" {
-		if err = compiler.createInitMainFunction(mainPkg, initmap); err != nil {
+	compiler.module.InitGraph = compiler.buildInitGraph(pkgs)
+	if importpath == "main" && !compiler.LinkTimeInit {
+		if err = compiler.createInitMainFunction(pkgs, initmap); err != nil {
 			return nil, fmt.Errorf("failed to create __go_init_main: %v", err)
 		}
-	} else {
+	} else if importpath != "main" {
 		compiler.module.ExportData = compiler.buildExportData(mainPkg, initmap)
 	}
+	compiler.timeCodegen(unit, start)
 
 	return compiler.module, nil
 }
 
+// writePartialIR writes the module, as built so far, to PartialIRFile,
+// preceded by a comment describing the failure.
+func (c *compiler) writePartialIR(failure string) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "; llgo: compilation of %s failed:\n", c.module.Path)
+	for _, line := range strings.Split(failure, "\n") {
+		fmt.Fprintf(&b, ";   %s\n", line)
+	}
+	fmt.Fprintf(&b, "; The partial bodies of functions whose code generation failed\n")
+	fmt.Fprintf(&b, "; are named with the suffix %s.\n\n", PartialIRSuffix)
+	b.WriteString(c.module.Module.String())
+	if err := ioutil.WriteFile(c.PartialIRFile, b.Bytes(), 0666); err != nil {
+		c.warnf(token.NoPos, "could not write partial IR: %v", err)
+	}
+}
+
+// timePhase reports the time since start as spent in the named phase, if
+// TimePhase is set, and returns the current time.
+func (c *compiler) timePhase(phase string, start time.Time) time.Time {
+	now := time.Now()
+	if c.TimePhase != nil {
+		c.TimePhase(phase, now.Sub(start))
+	}
+	return now
+}
+
+// timeCodegen reports the time since start as spent generating code: for
+// each source file, the time spent defining its functions, and then the
+// remainder (for type descriptors, synthetic functions and so on).
+func (c *compiler) timeCodegen(u *unit, start time.Time) {
+	if c.TimePhase == nil {
+		return
+	}
+	total := time.Since(start)
+	files := make([]string, 0, len(u.codegenTimes))
+	for file := range u.codegenTimes {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		c.TimePhase("codegen "+file, u.codegenTimes[file])
+		total -= u.codegenTimes[file]
+	}
+	c.TimePhase("codegen", total)
+}
+
 type byPriorityThenFunc []gccgoimporter.PackageInit
 
 func (a byPriorityThenFunc) Len() int      { return len(a) }
@@ -261,11 +577,46 @@ func (a byPriorityThenFunc) Less(i, j int) bool {
 	}
 }
 
-func (c *compiler) buildPackageInitData(mainPkg *ssa.Package, initmap map[*types.Package]gccgoimporter.InitData) gccgoimporter.InitData {
+// buildPackageInitData collects the init functions of the packages
+// compiled into the module and their dependencies, ordered by priority and
+// then by name. A package's priority is always greater than that of each of
+// its dependencies, so calling the init functions in this order respects
+// Go's initialization order regardless of the order in which packages are
+// linked. The packages in pkgs must be in dependency order, ending with the
+// package being compiled.
+func (c *compiler) buildPackageInitData(pkgs []*ssa.Package, initmap map[*types.Package]gccgoimporter.InitData) gccgoimporter.InitData {
+	local := make(map[*types.Package]bool, len(pkgs))
+	for _, pkg := range pkgs {
+		local[pkg.Object] = true
+	}
 	var inits []gccgoimporter.PackageInit
-	for _, imp := range mainPkg.Object.Imports() {
-		inits = append(inits, initmap[imp].Inits...)
+	for _, pkg := range pkgs {
+		for _, imp := range pkg.Object.Imports() {
+			if !local[imp] {
+				inits = append(inits, initmap[imp].Inits...)
+			}
+		}
+	}
+	uniqinits := sortPackageInits(inits)
+
+	ourprio := 0
+	if len(uniqinits) != 0 {
+		ourprio = uniqinits[len(uniqinits)-1].Priority
 	}
+	for _, pkg := range pkgs {
+		ourprio++
+		if imp := pkg.Func("init"); imp != nil {
+			impname := c.types.mc.mangleFunctionName(imp)
+			uniqinits = append(uniqinits, gccgoimporter.PackageInit{pkg.Object.Name(), impname, ourprio})
+		}
+	}
+
+	return gccgoimporter.InitData{ourprio, uniqinits}
+}
+
+// sortPackageInits sorts inits by priority and then by name, removing
+// duplicate entries.
+func sortPackageInits(inits []gccgoimporter.PackageInit) []gccgoimporter.PackageInit {
 	sort.Sort(byPriorityThenFunc(inits))
 
 	// Deduplicate init entries. We want to preserve the entry with the highest priority.
@@ -285,34 +636,33 @@ func (c *compiler) buildPackageInitData(mainPkg *ssa.Package, initmap map[*types
 			uniqinits[uniqinitpos] = init
 		}
 	}
-	uniqinits = uniqinits[uniqinitpos:]
-
-	ourprio := 1
-	if len(uniqinits) != 0 {
-		ourprio = uniqinits[len(uniqinits)-1].Priority + 1
-	}
-
-	if imp := mainPkg.Func("init"); imp != nil {
-		impname := c.types.mc.mangleFunctionName(imp)
-		uniqinits = append(uniqinits, gccgoimporter.PackageInit{mainPkg.Object.Name(), impname, ourprio})
-	}
-
-	return gccgoimporter.InitData{ourprio, uniqinits}
+	return uniqinits[uniqinitpos:]
 }
 
-func (c *compiler) createInitMainFunction(mainPkg *ssa.Package, initmap map[*types.Package]gccgoimporter.InitData) error {
-	initdata := c.buildPackageInitData(mainPkg, initmap)
+// createInitMainFunction creates the __go_init_main function, which the
+// runtime calls before main.main. Rather than relying on the order of
+// entries in llvm.global_ctors (which the runtime would not yet be ready
+// for), it calls each package's init function explicitly in the order
+// determined by buildPackageInitData.
+func (c *compiler) createInitMainFunction(pkgs []*ssa.Package, initmap map[*types.Package]gccgoimporter.InitData) error {
+	initdata := c.buildPackageInitData(pkgs, initmap)
+	c.defineInitMain(initdata.Inits)
+	return nil
+}
 
-	ftyp := llvm.FunctionType(llvm.VoidType(), nil, false)
+// defineInitMain defines the __go_init_main function, which calls the
+// given init functions in order.
+func (c *compiler) defineInitMain(inits []gccgoimporter.PackageInit) {
+	ftyp := llvm.FunctionType(c.llvmtypes.ctx.VoidType(), nil, false)
 	initMain := llvm.AddFunction(c.module.Module, "__go_init_main", ftyp)
 	c.addCommonFunctionAttrs(initMain)
-	entry := llvm.AddBasicBlock(initMain, "entry")
+	entry := c.llvmtypes.ctx.AddBasicBlock(initMain, "entry")
 
-	builder := llvm.GlobalContext().NewBuilder()
+	builder := c.llvmtypes.ctx.NewBuilder()
 	defer builder.Dispose()
 	builder.SetInsertPointAtEnd(entry)
 
-	for _, init := range initdata.Inits {
+	for _, init := range inits {
 		initfn := c.module.Module.NamedFunction(init.InitFunc)
 		if initfn.IsNil() {
 			initfn = llvm.AddFunction(c.module.Module, init.InitFunc, ftyp)
@@ -321,14 +671,13 @@ func (c *compiler) createInitMainFunction(mainPkg *ssa.Package, initmap map[*typ
 	}
 
 	builder.CreateRetVoid()
-	return nil
 }
 
 func (c *compiler) buildExportData(mainPkg *ssa.Package, initmap map[*types.Package]gccgoimporter.InitData) []byte {
 	exportData := importer.ExportData(mainPkg.Object)
 	b := bytes.NewBuffer(exportData)
 
-	initdata := c.buildPackageInitData(mainPkg, initmap)
+	initdata := c.buildPackageInitData([]*ssa.Package{mainPkg}, initmap)
 	b.WriteString("v1;\npriority ")
 	b.WriteString(strconv.Itoa(initdata.Priority))
 	b.WriteString(";\n")
@@ -346,6 +695,12 @@ func (c *compiler) buildExportData(mainPkg *ssa.Package, initmap map[*types.Pack
 		b.WriteString(";\n")
 	}
 
+	checksum := exportDataChecksum(b.Bytes())
+	b.Write(exportDataChecksumPrefix)
+	b.WriteString(exportDataChecksumMarker)
+	b.WriteString(checksum)
+	b.WriteString(";\n")
+
 	return b.Bytes()
 }
 
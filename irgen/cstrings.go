@@ -0,0 +1,70 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package irgen
+
+import (
+	"golang.org/x/tools/go/types"
+	"llvm.org/llvm/bindings/go/llvm"
+)
+
+// cstringAttribute marks a body-less function as making a NUL-terminated
+// copy of a Go string in memory allocated with C's malloc, like cgo's
+// C.CString. For example:
+//
+//	// #llgo cstring
+//	func cstring(s string) *int8
+//
+// The result must be a pointer. The caller owns the copy, and must release
+// it with C's free. The conversion is performed by libllgo's
+// __llgo_cstring, which panics if malloc fails.
+//
+// The _Cfunc_CString function declared by "go tool cgo -gccgo" is compiled
+// as if it had this attribute, so C.CString needs no C helper.
+type cstringAttribute struct{}
+
+func (cstringAttribute) Apply(v llvm.Value) {
+	applyBodylessAttribute("cstring", v)
+}
+
+// gostringAttribute marks a body-less function as copying a NUL-terminated
+// C string into a new Go string, like cgo's C.GoString, or, given a length
+// as well, copying that many bytes, like C.GoStringN. For example:
+//
+//	// #llgo gostring
+//	func gostring(p *int8) string
+//
+//	// #llgo gostring
+//	func gostringn(p *int8, n int32) string
+//
+// A nil pointer yields the empty string. The conversions are performed by
+// libllgo's __llgo_gostring and __llgo_gostringn; the latter panics if the
+// length is negative.
+//
+// Likewise, cgo's _Cfunc_GoString and _Cfunc_GoStringN are compiled as if
+// they had this attribute.
+type gostringAttribute struct{}
+
+func (gostringAttribute) Apply(v llvm.Value) {
+	applyBodylessAttribute("gostring", v)
+}
+
+// cstring copies the string s, and a terminating NUL, into memory
+// allocated with C's malloc, and returns a pointer to it of type typ.
+func (fr *frame) cstring(s *govalue, typ types.Type) *govalue {
+	ptr := fr.runtime.cstring.call(fr, s.value)[0]
+	return newValue(fr.builder.CreateBitCast(ptr, fr.types.ToLLVM(typ), ""), typ)
+}
+
+// gostring copies the C string at p into a new Go string. If n is nil, the
+// string is NUL-terminated; otherwise, n is the number of bytes to copy.
+func (fr *frame) gostring(p, n *govalue) *govalue {
+	i8ptr := llvm.PointerType(fr.llvmtypes.ctx.Int8Type(), 0)
+	ptr := fr.builder.CreateBitCast(p.value, i8ptr, "")
+	if n == nil {
+		return newValue(fr.runtime.gostring.call(fr, ptr)[0], types.Typ[types.String])
+	}
+	len := fr.convert(n, types.Typ[types.Int]).value
+	return newValue(fr.runtime.gostringn.call(fr, ptr, len)[0], types.Typ[types.String])
+}
@@ -5,6 +5,9 @@
 package irgen
 
 import (
+	"fmt"
+	"go/token"
+
 	"llvm.org/llvm/bindings/go/llvm"
 )
 
@@ -24,13 +27,28 @@ const (
 	gccgoRuntimeErrorCount                      = 11
 )
 
+// codegenError is the value of the panic by which an error in the source of
+// the function being compiled, such as a misuse of an attribute or a
+// construct that llgo does not support, abandons the function's code
+// generation. The error is reported at pos.
+type codegenError struct {
+	pos token.Pos
+	msg string
+}
+
+// errorf abandons code generation for the current function, reporting an
+// error at pos.
+func (fr *frame) errorf(pos token.Pos, format string, args ...interface{}) {
+	panic(codegenError{pos, fmt.Sprintf(format, args...)})
+}
+
 func (fr *frame) setBranchWeightMetadata(br llvm.Value, trueweight, falseweight uint64) {
-	mdprof := llvm.MDKindID("prof")
+	mdprof := fr.llvmtypes.ctx.MDKindID("prof")
 
-	mdnode := llvm.MDNode([]llvm.Value{
-		llvm.MDString("branch_weights"),
-		llvm.ConstInt(llvm.Int32Type(), trueweight, false),
-		llvm.ConstInt(llvm.Int32Type(), falseweight, false),
+	mdnode := fr.llvmtypes.ctx.MDNode([]llvm.Value{
+		fr.llvmtypes.ctx.MDString("branch_weights"),
+		llvm.ConstInt(fr.llvmtypes.ctx.Int32Type(), trueweight, false),
+		llvm.ConstInt(fr.llvmtypes.ctx.Int32Type(), falseweight, false),
 	})
 
 	br.SetMetadata(mdprof, mdnode)
@@ -44,18 +62,18 @@ func (fr *frame) condBrRuntimeError(cond llvm.Value, errcode uint64) {
 	errorbb := fr.runtimeErrorBlocks[errcode]
 	newbb := errorbb.C == nil
 	if newbb {
-		errorbb = llvm.AddBasicBlock(fr.function, "")
+		errorbb = fr.llvmtypes.ctx.AddBasicBlock(fr.function, "")
 		fr.runtimeErrorBlocks[errcode] = errorbb
 	}
 
-	contbb := llvm.AddBasicBlock(fr.function, "")
+	contbb := fr.llvmtypes.ctx.AddBasicBlock(fr.function, "")
 
 	br := fr.builder.CreateCondBr(cond, errorbb, contbb)
 	fr.setBranchWeightMetadata(br, 1, 1000)
 
 	if newbb {
 		fr.builder.SetInsertPointAtEnd(errorbb)
-		fr.runtime.runtimeError.call(fr, llvm.ConstInt(llvm.Int32Type(), errcode, false))
+		fr.runtime.runtimeError.call(fr, llvm.ConstInt(fr.llvmtypes.ctx.Int32Type(), errcode, false))
 		fr.builder.CreateUnreachable()
 	}
 
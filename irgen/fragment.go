@@ -0,0 +1,182 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package irgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/token"
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
+	"golang.org/x/tools/go/types"
+	"llvm.org/llvm/bindings/go/llvm"
+)
+
+// CompileFile compiles the package made up of the given files, as Compile
+// does, and returns the LLVM IR of the functions and global variables
+// declared in filename, which must be one of those files. Function literals
+// are included with the function that contains them.
+//
+// CompileFile is intended for tools that show the code generated for part
+// of a package. Each call compiles into a separate LLVM context, which is
+// disposed of before CompileFile returns.
+func (c *Compiler) CompileFile(filenames []string, importpath, filename string) (string, error) {
+	ir, _, err := c.compileFragment(filenames, importpath, func(pos token.Position, _ string) bool {
+		return pos.Filename == filename
+	})
+	return ir, err
+}
+
+// CompileDecl is like CompileFile, but returns the LLVM IR of a single
+// package-level function, method or global variable. Methods are named by
+// the name of their receiver's base type and the method name, as in "T.M".
+func (c *Compiler) CompileDecl(filenames []string, importpath, name string) (string, error) {
+	ir, n, err := c.compileFragment(filenames, importpath, func(_ token.Position, declname string) bool {
+		return declname == name
+	})
+	if err == nil && n == 0 {
+		err = fmt.Errorf("no function, method or variable named %s", name)
+	}
+	return ir, err
+}
+
+// compileFragment compiles the package and returns the IR of the
+// package-level declarations for which match returns true, along with the
+// number of such declarations.
+//
+// As the caller is typically a long-running tool, a panic during
+// compilation is returned as an error, after writing the partial IR if
+// requested, as Compiler.compile does.
+func (c *Compiler) compileFragment(filenames []string, importpath string, match func(pos token.Position, name string) bool) (string, int, error) {
+	ctx := llvm.NewContext()
+	defer ctx.Dispose()
+	compiler := c.newCompiler(ctx)
+	m, err := c.compileFragmentModule(compiler, filenames, importpath)
+	if err != nil {
+		return "", 0, err
+	}
+	defer m.Module.Dispose()
+	syms, n := compiler.unit.declSymbols(match)
+	return extractIR(m.Module.String(), syms), n, nil
+}
+
+// compileFragmentModule compiles the package with compiler, disposing of
+// the module and converting a panic into an error if compilation fails.
+func (c *Compiler) compileFragmentModule(compiler *compiler, filenames []string, importpath string) (m *Module, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+			if compiler.module != nil {
+				if c.opts.PartialIRFile != "" {
+					compiler.writePartialIR(err.Error())
+				}
+				compiler.module.Module.Dispose()
+			}
+			m = nil
+		}
+	}()
+	m, err = compiler.compile(filenames, importpath)
+	if err != nil && compiler.module != nil {
+		if c.opts.PartialIRFile != "" {
+			compiler.writePartialIR(err.Error())
+		}
+		compiler.module.Module.Dispose()
+	}
+	return m, err
+}
+
+// declSymbols returns the names of the LLVM functions and globals generated
+// for the package-level declarations of u's package for which match
+// returns true, and the number of such declarations.
+func (u *unit) declSymbols(match func(pos token.Position, name string) bool) (map[string]bool, int) {
+	syms := make(map[string]bool)
+	var addFunction func(f *ssa.Function)
+	addFunction = func(f *ssa.Function) {
+		if v, ok := u.globals[f]; ok {
+			syms[v.Name()] = true
+			syms[v.Name()+"$recover"] = true
+		}
+		for _, anon := range f.AnonFuncs {
+			addFunction(anon)
+		}
+	}
+
+	n := 0
+	for f := range ssautil.AllFunctions(u.pkg.Prog) {
+		if f.Pkg != u.pkg || f.Synthetic != "" || f.Parent() != nil {
+			continue
+		}
+		name := f.Name()
+		if recv := f.Signature.Recv(); recv != nil {
+			if named, ok := deref(recv.Type()).(*types.Named); ok {
+				name = named.Obj().Name() + "." + name
+			}
+		}
+		if match(u.fileset.Position(f.Pos()), name) {
+			addFunction(f)
+			n++
+		}
+	}
+	for _, member := range u.pkg.Members {
+		g, ok := member.(*ssa.Global)
+		if !ok || !match(u.fileset.Position(g.Pos()), g.Name()) {
+			continue
+		}
+		if v, ok := u.globals[g]; ok {
+			syms[v.Name()] = true
+		}
+		n++
+	}
+	return syms, n
+}
+
+// extractIR returns the function definitions and global variables in the
+// textual module ir whose names are in syms, in module order.
+func extractIR(ir string, syms map[string]bool) string {
+	var buf bytes.Buffer
+	lines := strings.SplitAfter(ir, "\n")
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case strings.HasPrefix(line, "define "):
+			end := i
+			for end < len(lines)-1 && strings.TrimSpace(lines[end]) != "}" {
+				end++
+			}
+			if at := strings.Index(line, "@"); at != -1 && syms[irSymbolName(line[at:])] {
+				if buf.Len() != 0 {
+					buf.WriteString("\n")
+				}
+				for _, l := range lines[i : end+1] {
+					buf.WriteString(l)
+				}
+			}
+			i = end
+		case strings.HasPrefix(line, "@"):
+			if syms[irSymbolName(line)] {
+				buf.WriteString(line)
+			}
+		}
+	}
+	return buf.String()
+}
+
+// irSymbolName returns the name of the global referenced at the start of
+// s, which begins with "@". Quoted names are returned without quotes.
+func irSymbolName(s string) string {
+	s = s[1:]
+	if strings.HasPrefix(s, `"`) {
+		if end := strings.Index(s[1:], `"`); end != -1 {
+			return s[1 : end+1]
+		}
+		return s[1:]
+	}
+	if end := strings.IndexAny(s, " (=,"); end != -1 {
+		return s[:end]
+	}
+	return strings.TrimSpace(s)
+}
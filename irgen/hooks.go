@@ -0,0 +1,113 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package irgen
+
+import (
+	"llvm.org/llvm/bindings/go/llvm"
+)
+
+// installRuntimeHooks makes generated code call the runtime functions that
+// embedders in constrained environments most often need to intercept
+// through hooks, which are weak definitions that forward to the runtime
+// functions:
+//
+//	__llgo_hook_new             (__go_new: allocate memory that may hold pointers)
+//	__llgo_hook_new_nopointers  (__go_new_nopointers: allocate pointer-free memory)
+//	__llgo_hook_go              (__go_go: start a goroutine)
+//	__llgo_hook_panic           (__go_panic: begin panicking)
+//
+// These hooks see only the calls made by code that llgo generates, and not
+// those that libgo makes internally, so a replacement must forward to the
+// runtime function rather than reimplement it: memory that does not come
+// from __go_new is unknown to the garbage collector. They are intended for
+// accounting, tracing, and failing fast in environments with tight limits.
+//
+// If mainPackage is set, hooks on the functions that libgo itself calls to
+// create threads and to write the runtime's output are also defined:
+//
+//	__llgo_hook_create_thread   (pthread_create)
+//	__llgo_hook_write           (runtime_write: int32 runtime_write(uintptr
+//	                             fd, const void *buf, int32 n), through
+//	                             which the runtime writes the output of
+//	                             print and println, and the messages of
+//	                             unrecovered panics)
+//
+// Other writes, such as those of the os package and of C libraries, do not
+// reach __llgo_hook_write. libgo's calls reach these hooks when a program
+// is linked with the flags returned by RuntimeHookLinkerFlags, and libgo is
+// linked statically.
+//
+// An embedder may replace a hook by linking in a strong definition of it
+// with the same C signature as the function it hooks. Hooks carry the
+// function attributes of the functions they forward to (for example,
+// __llgo_hook_panic does not return), which replacements must honour.
+func (c *compiler) installRuntimeHooks(mainPackage bool) {
+	for _, h := range [...]struct {
+		name string
+		rfi  *runtimeFnInfo
+	}{
+		{"__llgo_hook_new", &c.runtime.New},
+		{"__llgo_hook_new_nopointers", &c.runtime.NewNopointers},
+		{"__llgo_hook_go", &c.runtime.Go},
+		{"__llgo_hook_panic", &c.runtime.panic},
+	} {
+		h.rfi.fn = c.defineRuntimeHook(h.name, h.rfi.fn)
+	}
+	if !mainPackage {
+		return
+	}
+
+	ctx := c.llvmtypes.ctx
+	i8ptr := llvm.PointerType(ctx.Int8Type(), 0)
+	for _, h := range [...]struct {
+		name, function string
+		ftyp           llvm.Type
+	}{
+		{"__llgo_hook_create_thread", "pthread_create",
+			llvm.FunctionType(ctx.Int32Type(), []llvm.Type{i8ptr, i8ptr, i8ptr, i8ptr}, false)},
+		{"__llgo_hook_write", "runtime_write",
+			llvm.FunctionType(ctx.Int32Type(), []llvm.Type{c.llvmtypes.inttype, i8ptr, ctx.Int32Type()}, false)},
+	} {
+		// With --wrap=function, the linker resolves references to the
+		// function to __wrap_function, and references to
+		// __real_function to the function itself.
+		real := llvm.AddFunction(c.module.Module, "__real_"+h.function, h.ftyp)
+		hook := c.defineRuntimeHook(h.name, real)
+		c.defineRuntimeHook("__wrap_"+h.function, hook)
+	}
+}
+
+// RuntimeHookLinkerFlags returns the flags to pass to the compiler driver
+// when linking a program whose main package was compiled with
+// CompilerOptions.RuntimeHooks, so that libgo's calls to the functions
+// that have hooks are routed through them.
+func RuntimeHookLinkerFlags() []string {
+	return []string{"-Wl,--wrap=pthread_create", "-Wl,--wrap=runtime_write"}
+}
+
+// defineRuntimeHook defines a weak function with the given name that
+// forwards its arguments to fn, and returns it.
+func (c *compiler) defineRuntimeHook(name string, fn llvm.Value) llvm.Value {
+	hook := llvm.AddFunction(c.module.Module, name, fn.Type().ElementType())
+	hook.SetLinkage(llvm.WeakAnyLinkage)
+	c.addCommonFunctionAttrs(hook)
+	if attr := fn.FunctionAttr(); attr != 0 {
+		hook.AddFunctionAttr(attr)
+	}
+
+	builder := c.llvmtypes.ctx.NewBuilder()
+	defer builder.Dispose()
+	builder.SetInsertPointAtEnd(c.llvmtypes.ctx.AddBasicBlock(hook, "entry"))
+	result := builder.CreateCall(fn, hook.Params(), "")
+	switch {
+	case fn.FunctionAttr()&llvm.NoReturnAttribute != 0:
+		builder.CreateUnreachable()
+	case fn.Type().ElementType().ReturnType().TypeKind() == llvm.VoidTypeKind:
+		builder.CreateRetVoid()
+	default:
+		builder.CreateRet(result)
+	}
+	return hook
+}
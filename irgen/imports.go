@@ -0,0 +1,60 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package irgen
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/types"
+)
+
+// checkImportCycles reports an error if the package with the given import
+// path, consisting of files, imports itself, either directly or through the
+// export data of the packages it imports. The latter can happen if an
+// imported package was compiled against an earlier version of this one.
+func checkImportCycles(fset *token.FileSet, files []*ast.File, importpath string) error {
+	for _, file := range files {
+		for _, spec := range file.Imports {
+			path, err := strconv.Unquote(spec.Path.Value)
+			if err == nil && path == importpath {
+				return fmt.Errorf("%s: import cycle not allowed: %s imports itself", fset.Position(spec.Pos()), importpath)
+			}
+		}
+	}
+	return nil
+}
+
+// checkImportedCycles reports an error if any package imported by pkg,
+// directly or indirectly, imports pkg, giving the full cycle.
+func checkImportedCycles(pkg *types.Package) error {
+	visited := make(map[*types.Package]bool)
+	var path []string
+	var visit func(p *types.Package) bool
+	visit = func(p *types.Package) bool {
+		path = append(path, p.Path())
+		for _, imp := range p.Imports() {
+			if imp.Path() == pkg.Path() {
+				path = append(path, imp.Path())
+				return true
+			}
+			if !visited[imp] {
+				visited[imp] = true
+				if visit(imp) {
+					return true
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		return false
+	}
+	if visit(pkg) {
+		return fmt.Errorf("import cycle not allowed: %s", strings.Join(path, " -> "))
+	}
+	return nil
+}
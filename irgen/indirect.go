@@ -38,7 +38,7 @@ func (fr *frame) createThunk(call ssa.CallInstruction) (thunk llvm.Value, arg ll
 	}
 
 	var isRecoverCall bool
-	i8ptr := llvm.PointerType(llvm.Int8Type(), 0)
+	i8ptr := llvm.PointerType(fr.llvmtypes.ctx.Int8Type(), 0)
 	var structllptr llvm.Type
 	if len(args) == 0 {
 		if builtin, ok := call.Common().Value.(*ssa.Builtin); ok {
@@ -46,7 +46,7 @@ func (fr *frame) createThunk(call ssa.CallInstruction) (thunk llvm.Value, arg ll
 		}
 		if isRecoverCall {
 			// When creating a thunk for recover(), we must pass fr.canRecover.
-			arg = fr.builder.CreateZExt(fr.canRecover, fr.target.IntPtrType(), "")
+			arg = fr.builder.CreateZExt(fr.canRecover, fr.llvmtypes.inttype, "")
 			arg = fr.builder.CreateIntToPtr(arg, i8ptr, "")
 		} else {
 			arg = llvm.ConstPointerNull(i8ptr)
@@ -62,7 +62,7 @@ func (fr *frame) createThunk(call ssa.CallInstruction) (thunk llvm.Value, arg ll
 		arg = fr.builder.CreateBitCast(arg, i8ptr, "")
 	}
 
-	thunkfntype := llvm.FunctionType(llvm.VoidType(), []llvm.Type{i8ptr}, false)
+	thunkfntype := llvm.FunctionType(fr.llvmtypes.ctx.VoidType(), []llvm.Type{i8ptr}, false)
 	thunkfn := llvm.AddFunction(fr.module.Module, "", thunkfntype)
 	thunkfn.SetLinkage(llvm.InternalLinkage)
 	fr.addCommonFunctionAttrs(thunkfn)
@@ -70,13 +70,13 @@ func (fr *frame) createThunk(call ssa.CallInstruction) (thunk llvm.Value, arg ll
 	thunkfr := newFrame(fr.unit, thunkfn)
 	defer thunkfr.dispose()
 
-	prologuebb := llvm.AddBasicBlock(thunkfn, "prologue")
+	prologuebb := fr.llvmtypes.ctx.AddBasicBlock(thunkfn, "prologue")
 	thunkfr.builder.SetInsertPointAtEnd(prologuebb)
 
 	if isRecoverCall {
 		thunkarg := thunkfn.Param(0)
-		thunkarg = thunkfr.builder.CreatePtrToInt(thunkarg, fr.target.IntPtrType(), "")
-		thunkfr.canRecover = thunkfr.builder.CreateTrunc(thunkarg, llvm.Int1Type(), "")
+		thunkarg = thunkfr.builder.CreatePtrToInt(thunkarg, fr.llvmtypes.inttype, "")
+		thunkfr.canRecover = thunkfr.builder.CreateTrunc(thunkarg, fr.llvmtypes.ctx.Int1Type(), "")
 	} else if len(args) > 0 {
 		thunkarg := thunkfn.Param(0)
 		thunkarg = thunkfr.builder.CreateBitCast(thunkarg, structllptr, "")
@@ -89,14 +89,14 @@ func (fr *frame) createThunk(call ssa.CallInstruction) (thunk llvm.Value, arg ll
 
 	_, isDefer := call.(*ssa.Defer)
 
-	entrybb := llvm.AddBasicBlock(thunkfn, "entry")
+	entrybb := fr.llvmtypes.ctx.AddBasicBlock(thunkfn, "entry")
 	br := thunkfr.builder.CreateBr(entrybb)
 	thunkfr.allocaBuilder.SetInsertPointBefore(br)
 
 	thunkfr.builder.SetInsertPointAtEnd(entrybb)
 	var exitbb llvm.BasicBlock
 	if isDefer {
-		exitbb = llvm.AddBasicBlock(thunkfn, "exit")
+		exitbb = fr.llvmtypes.ctx.AddBasicBlock(thunkfn, "exit")
 		thunkfr.runtime.setDeferRetaddr.call(thunkfr, llvm.BlockAddress(thunkfn, exitbb))
 	}
 	if isDefer && isRecoverCall {
@@ -104,6 +104,8 @@ func (fr *frame) createThunk(call ssa.CallInstruction) (thunk llvm.Value, arg ll
 	} else {
 		thunkfr.callInstruction(call)
 	}
+	// The call may have terminated the block (e.g. "defer panic(x)").
+	thunkfr.ensureBlockOpen()
 	if isDefer {
 		thunkfr.builder.CreateBr(exitbb)
 		thunkfr.builder.SetInsertPointAtEnd(exitbb)
@@ -0,0 +1,178 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package irgen
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/gccgoimporter"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/types"
+	"llvm.org/llvm/bindings/go/llvm"
+)
+
+// InitGraphSection is the name of the object file section in which the
+// driver stores the initialization graph (Module.InitGraph) of the
+// packages compiled into the object.
+const InitGraphSection = ".go_init"
+
+// An InitNode describes the initialization of a package: its init
+// function, and the packages it imports, which must be initialized first.
+type InitNode struct {
+	Path     string
+	Name     string
+	InitFunc string
+	Imports  []string
+}
+
+// buildInitGraph returns the encoded initialization graph of pkgs.
+func (c *compiler) buildInitGraph(pkgs []*ssa.Package) []byte {
+	nodes := make([]InitNode, len(pkgs))
+	for i, pkg := range pkgs {
+		nodes[i] = InitNode{
+			Path:     pkg.Object.Path(),
+			Name:     pkg.Object.Name(),
+			InitFunc: c.types.mc.mangleFunctionName(pkg.Func("init")),
+		}
+		for _, imp := range pkg.Object.Imports() {
+			if imp.Path() != "unsafe" {
+				nodes[i].Imports = append(nodes[i].Imports, imp.Path())
+			}
+		}
+		sort.Strings(nodes[i].Imports)
+	}
+	return EncodeInitGraph(nodes)
+}
+
+// EncodeInitGraph encodes nodes in the form stored in object files. The
+// encodings of several graphs may be concatenated, and decoded together.
+func EncodeInitGraph(nodes []InitNode) []byte {
+	var b bytes.Buffer
+	b.WriteString("v1;\n")
+	for _, n := range nodes {
+		fmt.Fprintf(&b, "package %s %s %s", n.Path, n.Name, n.InitFunc)
+		for _, imp := range n.Imports {
+			b.WriteRune(' ')
+			b.WriteString(imp)
+		}
+		b.WriteString(";\n")
+	}
+	return b.Bytes()
+}
+
+// DecodeInitGraph decodes the nodes encoded in data by EncodeInitGraph.
+func DecodeInitGraph(data []byte) ([]InitNode, error) {
+	var nodes []InitNode
+	for _, line := range strings.Split(string(data), "\n") {
+		// Sections may be padded with NULs.
+		line = strings.Trim(line, "\x00")
+		if line == "" || line == "v1;" {
+			continue
+		}
+		fields := strings.Fields(strings.TrimSuffix(line, ";"))
+		if !strings.HasSuffix(line, ";") || len(fields) < 4 || fields[0] != "package" {
+			return nil, fmt.Errorf("invalid initialization graph entry %q", line)
+		}
+		nodes = append(nodes, InitNode{
+			Path:     fields[1],
+			Name:     fields[2],
+			InitFunc: fields[3],
+			Imports:  fields[4:],
+		})
+	}
+	return nodes, nil
+}
+
+// CompileInitMain returns a module defining the __go_init_main function of
+// a program whose packages were compiled with the LinkTimeInit option,
+// given the initialization graphs of the linked objects. The init
+// functions of packages imported by the graph but absent from it (such as
+// those of the standard library) are read from export data, and called
+// first; then those of the packages in the graph are called, each after
+// those of the packages it imports. The caller owns the resulting Module,
+// and must call its Dispose method when done with it.
+func (c *Compiler) CompileInitMain(nodes []InitNode) (*Module, error) {
+	byPath := make(map[string]*InitNode, len(nodes))
+	var paths []string
+	for i := range nodes {
+		if _, ok := byPath[nodes[i].Path]; !ok {
+			byPath[nodes[i].Path] = &nodes[i]
+			paths = append(paths, nodes[i].Path)
+		}
+	}
+	if _, ok := byPath["main"]; !ok {
+		return nil, fmt.Errorf("no initialization graph for package main")
+	}
+	sort.Strings(paths)
+
+	// Order the packages in the graph so that each follows its imports,
+	// and collect the imports that are not in the graph.
+	var ordered []*InitNode
+	var external []string
+	state := make(map[string]int) // 1: visiting, 2: visited
+	var visit func(path string) error
+	visit = func(path string) error {
+		switch state[path] {
+		case 1:
+			return fmt.Errorf("import cycle involving package %s in initialization graph", path)
+		case 2:
+			return nil
+		}
+		state[path] = 1
+		if n := byPath[path]; n != nil {
+			for _, imp := range n.Imports {
+				if err := visit(imp); err != nil {
+					return err
+				}
+			}
+			ordered = append(ordered, n)
+		} else {
+			external = append(external, path)
+		}
+		state[path] = 2
+		return nil
+	}
+	for _, path := range paths {
+		if err := visit(path); err != nil {
+			return nil, err
+		}
+	}
+
+	initmap := make(map[*types.Package]gccgoimporter.InitData)
+	importer, err := newImporter(&c.opts, initmap)
+	if err != nil {
+		return nil, err
+	}
+	imports := make(map[string]*types.Package)
+	var inits []gccgoimporter.PackageInit
+	for _, path := range external {
+		pkg, err := importer(imports, path)
+		if err != nil {
+			return nil, fmt.Errorf("no initialization data for package %s: %v", path, err)
+		}
+		inits = append(inits, initmap[pkg].Inits...)
+	}
+	inits = sortPackageInits(inits)
+
+	prio := 0
+	if len(inits) != 0 {
+		prio = inits[len(inits)-1].Priority
+	}
+	for _, n := range ordered {
+		prio++
+		inits = append(inits, gccgoimporter.PackageInit{n.Name, n.InitFunc, prio})
+	}
+
+	ctx := llvm.NewContext()
+	compiler := c.newCompiler(ctx)
+	compiler.module = &Module{Module: ctx.NewModule("__go_init_main"), Path: "main", ctx: ctx}
+	compiler.module.SetTarget(compiler.TargetTriple)
+	compiler.module.SetDataLayout(compiler.dataLayout)
+	compiler.defineInitMain(inits)
+	return compiler.module, nil
+}
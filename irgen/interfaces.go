@@ -14,22 +14,14 @@ import (
 func (fr *frame) interfaceMethod(lliface llvm.Value, ifacety types.Type, method *types.Func) (fn, recv *govalue) {
 	llitab := fr.builder.CreateExtractValue(lliface, 0, "")
 	recv = newValue(fr.builder.CreateExtractValue(lliface, 1, ""), types.Typ[types.UnsafePointer])
-	methodset := fr.types.MethodSet(ifacety)
-	// TODO(axw) cache ordered method index
-	index := -1
-	for i, m := range orderedMethodSet(methodset) {
-		if m.Obj() == method {
-			index = i
-			break
-		}
-	}
+	index := fr.types.MethodIndex(ifacety, method)
 	if index == -1 {
 		panic("could not find method index")
 	}
-	llitab = fr.builder.CreateBitCast(llitab, llvm.PointerType(llvm.PointerType(llvm.Int8Type(), 0), 0), "")
+	llitab = fr.builder.CreateBitCast(llitab, llvm.PointerType(llvm.PointerType(fr.llvmtypes.ctx.Int8Type(), 0), 0), "")
 	// Skip runtime type pointer.
 	llifnptr := fr.builder.CreateGEP(llitab, []llvm.Value{
-		llvm.ConstInt(llvm.Int32Type(), uint64(index+1), false),
+		llvm.ConstInt(fr.llvmtypes.ctx.Int32Type(), uint64(index+1), false),
 	}, "")
 
 	llifn := fr.builder.CreateLoad(llifnptr, "")
@@ -47,7 +39,21 @@ func (fr *frame) compareInterfaces(a, b *govalue) *govalue {
 	aNull := a.value.IsNull()
 	bNull := b.value.IsNull()
 	if aNull && bNull {
-		return newValue(boolLLVMValue(true), types.Typ[types.Bool])
+		return newValue(fr.boolLLVMValue(true), types.Typ[types.Bool])
+	}
+
+	// A comparison against a nil interface (for example, "case nil" in an
+	// expression switch) only needs to check the type word; there is no
+	// need to call into the runtime.
+	if aNull || bNull {
+		v := a
+		if aNull {
+			v = b
+		}
+		itab := fr.builder.CreateExtractValue(v.value, 0, "")
+		result := fr.builder.CreateIsNull(itab, "")
+		result = fr.builder.CreateZExt(result, fr.llvmtypes.ctx.Int8Type(), "")
+		return newValue(result, types.Typ[types.Bool])
 	}
 
 	compare := fr.runtime.emptyInterfaceCompare
@@ -64,7 +70,7 @@ func (fr *frame) compareInterfaces(a, b *govalue) *govalue {
 
 	result := compare.call(fr, a.value, b.value)[0]
 	result = fr.builder.CreateIsNull(result, "")
-	result = fr.builder.CreateZExt(result, llvm.Int8Type(), "")
+	result = fr.builder.CreateZExt(result, fr.llvmtypes.ctx.Int8Type(), "")
 	return newValue(result, types.Typ[types.Bool])
 }
 
@@ -78,7 +84,7 @@ func (fr *frame) makeInterface(llv llvm.Value, vty types.Type, iface types.Type)
 }
 
 func (fr *frame) makeInterfaceFromPointer(vptr llvm.Value, vty types.Type, iface types.Type) *govalue {
-	i8ptr := llvm.PointerType(llvm.Int8Type(), 0)
+	i8ptr := llvm.PointerType(fr.llvmtypes.ctx.Int8Type(), 0)
 	llv := fr.builder.CreateBitCast(vptr, i8ptr, "")
 	value := llvm.Undef(fr.types.ToLLVM(iface))
 	itab := fr.types.getItabPointer(vty, iface.Underlying().(*types.Interface))
@@ -137,7 +143,7 @@ func (fr *frame) interfaceTypeCheck(val *govalue, ty types.Type) (v *govalue, ok
 		valtd := fr.getInterfaceTypeDescriptor(val)
 		tyequal := fr.runtime.typeDescriptorsEqual.call(fr, valtd, tytd)[0]
 		okval = newValue(tyequal, types.Typ[types.Bool])
-		tyequal = fr.builder.CreateTrunc(tyequal, llvm.Int1Type(), "")
+		tyequal = fr.builder.CreateTrunc(tyequal, fr.llvmtypes.ctx.Int1Type(), "")
 
 		v = fr.getInterfaceValueOrNull(tyequal, val, ty)
 	}
@@ -169,6 +175,11 @@ func (fr *frame) convertI2E(v *govalue) *govalue {
 	return newValue(intf, typ)
 }
 
+// changeInterface converts an interface value to another interface type.
+// The method table for the dynamic type is not known statically, so the
+// runtime builds a new one for each conversion. All type descriptors and
+// method tables emitted by the compiler are constant data, so nothing is
+// constructed lazily, and concurrent conversions share no mutable state.
 func (fr *frame) changeInterface(v *govalue, ty types.Type, assert bool) *govalue {
 	td := fr.getInterfaceTypeDescriptor(v)
 	tytd := fr.types.ToRuntime(ty)
@@ -0,0 +1,192 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package irgen
+
+import (
+	"go/token"
+	"math"
+
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/types"
+	"llvm.org/llvm/bindings/go/llvm"
+)
+
+// An intrinsic generates inline code for a call to a library function,
+// in place of a call to the function's Go (or C) implementation.
+type intrinsic func(fr *frame, args []*govalue) *govalue
+
+// intrinsicInfo describes an intrinsic implementation of a function, which
+// is used only if the function has the signature sig.
+type intrinsicInfo struct {
+	impl intrinsic
+	sig  *types.Signature
+}
+
+var (
+	float64Type   = types.Typ[types.Float64]
+	byteSliceType = types.NewSlice(types.Typ[types.Byte])
+
+	unaryFloat64Sig  = intrinsicSignature([]types.Type{float64Type}, float64Type)
+	binaryFloat64Sig = intrinsicSignature([]types.Type{float64Type, float64Type}, float64Type)
+)
+
+// intrinsics maps the package paths and names of functions to their
+// intrinsic implementations.
+var intrinsics = map[string]intrinsicInfo{
+	"bytes.IndexByte":   {(*frame).indexByte, intrinsicSignature([]types.Type{byteSliceType, types.Typ[types.Byte]}, types.Typ[types.Int])},
+	"math.Abs":          {llvmIntrinsic("llvm.fabs.f64"), unaryFloat64Sig},
+	"math.Ceil":         {llvmIntrinsic("llvm.ceil.f64"), unaryFloat64Sig},
+	"math.Copysign":     {llvmIntrinsic("llvm.copysign.f64"), binaryFloat64Sig},
+	"math.Floor":        {llvmIntrinsic("llvm.floor.f64"), unaryFloat64Sig},
+	"math.Max":          {(*frame).mathMax, binaryFloat64Sig},
+	"math.Min":          {(*frame).mathMin, binaryFloat64Sig},
+	"math.Sqrt":         {(*frame).mathSqrt, unaryFloat64Sig},
+	"math.Trunc":        {llvmIntrinsic("llvm.trunc.f64"), unaryFloat64Sig},
+	"strings.IndexByte": {(*frame).indexByte, intrinsicSignature([]types.Type{types.Typ[types.String], types.Typ[types.Byte]}, types.Typ[types.Int])},
+}
+
+// intrinsicSignature returns the signature of a function with the given
+// parameter types and a single result.
+func intrinsicSignature(params []types.Type, result types.Type) *types.Signature {
+	vars := make([]*types.Var, len(params))
+	for i, param := range params {
+		vars[i] = types.NewParam(token.NoPos, nil, "", param)
+	}
+	results := types.NewTuple(types.NewParam(token.NoPos, nil, "", result))
+	return types.NewSignature(nil, nil, types.NewTuple(vars...), results, false)
+}
+
+// callIntrinsic generates inline code for a call to f if f has an intrinsic
+// implementation, and reports whether it did so. A function is only
+// replaced if both its package path and name and its signature match, so
+// that a function of another package with the same name is called
+// normally.
+func (fr *frame) callIntrinsic(f *ssa.Function, args []*govalue) ([]*govalue, bool) {
+	if f.Pkg == nil || f.Signature.Recv() != nil {
+		return nil, false
+	}
+	info, ok := intrinsics[f.Pkg.Object.Path()+"."+f.Name()]
+	if !ok || !types.Identical(f.Signature, info.sig) {
+		return nil, false
+	}
+	return []*govalue{info.impl(fr, args)}, true
+}
+
+// llvmIntrinsic returns an intrinsic that calls the named LLVM intrinsic
+// function, whose parameter and result types match those of the Go function.
+func llvmIntrinsic(name string) intrinsic {
+	return func(fr *frame, args []*govalue) *govalue {
+		return newValue(fr.callLLVMIntrinsic(name, args), args[0].Type())
+	}
+}
+
+func (fr *frame) callLLVMIntrinsic(name string, args []*govalue) llvm.Value {
+	llargs := make([]llvm.Value, len(args))
+	lltypes := make([]llvm.Type, len(args))
+	for i, arg := range args {
+		llargs[i] = arg.value
+		lltypes[i] = arg.value.Type()
+	}
+	fn := fr.module.NamedFunction(name)
+	if fn.IsNil() {
+		ftyp := llvm.FunctionType(lltypes[0], lltypes, false)
+		fn = llvm.AddFunction(fr.module.Module, name, ftyp)
+	}
+	return fr.builder.CreateCall(fn, llargs, "")
+}
+
+// mathSqrt implements math.Sqrt using llvm.sqrt. The result of llvm.sqrt is
+// undefined for negative operands, whereas math.Sqrt must return NaN.
+func (fr *frame) mathSqrt(args []*govalue) *govalue {
+	x := args[0].value
+	sqrt := fr.callLLVMIntrinsic("llvm.sqrt.f64", args)
+	zero := llvm.ConstNull(x.Type())
+	neg := fr.builder.CreateFCmp(llvm.FloatOLT, x, zero, "")
+	nan := fr.builder.CreateFDiv(zero, zero, "")
+	result := fr.builder.CreateSelect(neg, nan, sqrt, "")
+	return newValue(result, args[0].Type())
+}
+
+func (fr *frame) mathMax(args []*govalue) *govalue {
+	return fr.mathMinMax(args, true)
+}
+
+func (fr *frame) mathMin(args []*govalue) *govalue {
+	return fr.mathMinMax(args, false)
+}
+
+// mathMinMax implements math.Max and math.Min inline. LLVM's maxnum and
+// minnum intrinsics cannot be used, as they do not propagate NaNs or order
+// signed zeros as Go requires.
+func (fr *frame) mathMinMax(args []*govalue, max bool) *govalue {
+	x, y := args[0].value, args[1].value
+	i64 := fr.llvmtypes.ctx.Int64Type()
+	xbits := fr.builder.CreateBitCast(x, i64, "")
+	ybits := fr.builder.CreateBitCast(y, i64, "")
+
+	// If x == y, the operands differ at most in the sign of zero:
+	// Max(+0, -0) is +0 and Min(+0, -0) is -0, which we get by combining
+	// the sign bits.
+	var eqbits, pick llvm.Value
+	if max {
+		eqbits = fr.builder.CreateAnd(xbits, ybits, "")
+		pick = fr.builder.CreateFCmp(llvm.FloatOGT, x, y, "")
+	} else {
+		eqbits = fr.builder.CreateOr(xbits, ybits, "")
+		pick = fr.builder.CreateFCmp(llvm.FloatOLT, x, y, "")
+	}
+	result := fr.builder.CreateSelect(pick, x, y, "")
+	eq := fr.builder.CreateFCmp(llvm.FloatOEQ, x, y, "")
+	eqval := fr.builder.CreateBitCast(eqbits, x.Type(), "")
+	result = fr.builder.CreateSelect(eq, eqval, result, "")
+
+	// If either operand is NaN, so is the result.
+	uno := fr.builder.CreateFCmp(llvm.FloatUNO, x, y, "")
+	nan := fr.builder.CreateFAdd(x, y, "")
+	result = fr.builder.CreateSelect(uno, nan, result, "")
+
+	// Max(x, +Inf) is +Inf and Min(x, -Inf) is -Inf, even if x is NaN.
+	inf := llvm.ConstFloat(x.Type(), math.Inf(1))
+	if !max {
+		inf = llvm.ConstFloat(x.Type(), math.Inf(-1))
+	}
+	xinf := fr.builder.CreateFCmp(llvm.FloatOEQ, x, inf, "")
+	yinf := fr.builder.CreateFCmp(llvm.FloatOEQ, y, inf, "")
+	isinf := fr.builder.CreateOr(xinf, yinf, "")
+	result = fr.builder.CreateSelect(isinf, inf, result, "")
+	return newValue(result, args[0].Type())
+}
+
+// getMemchr returns a declaration of the C library's memchr function.
+func (fr *frame) getMemchr() llvm.Value {
+	memchr := fr.module.NamedFunction("memchr")
+	if memchr.IsNil() {
+		i8ptr := llvm.PointerType(fr.llvmtypes.ctx.Int8Type(), 0)
+		ftyp := llvm.FunctionType(i8ptr, []llvm.Type{i8ptr, fr.llvmtypes.ctx.Int32Type(), fr.llvmtypes.inttype}, false)
+		memchr = llvm.AddFunction(fr.module.Module, "memchr", ftyp)
+		memchr.AddFunctionAttr(llvm.NoUnwindAttribute)
+		memchr.AddFunctionAttr(llvm.ReadOnlyAttribute)
+	}
+	return memchr
+}
+
+// indexByte implements strings.IndexByte and bytes.IndexByte using memchr.
+// Both strings and slices begin with a data pointer and a length, so the
+// same code serves for each.
+func (fr *frame) indexByte(args []*govalue) *govalue {
+	s, c := args[0], args[1]
+	ptr := fr.builder.CreateExtractValue(s.value, 0, "")
+	n := fr.builder.CreateExtractValue(s.value, 1, "")
+	n = fr.createZExtOrTrunc(n, fr.llvmtypes.inttype, "")
+	cint := fr.builder.CreateZExt(c.value, fr.llvmtypes.ctx.Int32Type(), "")
+	found := fr.builder.CreateCall(fr.getMemchr(), []llvm.Value{ptr, cint, n}, "")
+
+	notfound := fr.builder.CreateIsNull(found, "")
+	foundint := fr.builder.CreatePtrToInt(found, fr.types.inttype, "")
+	ptrint := fr.builder.CreatePtrToInt(ptr, fr.types.inttype, "")
+	index := fr.builder.CreateSub(foundint, ptrint, "")
+	index = fr.builder.CreateSelect(notfound, llvm.ConstAllOnes(fr.types.inttype), index, "")
+	return newValue(index, types.Typ[types.Int])
+}
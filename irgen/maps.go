@@ -5,6 +5,7 @@
 package irgen
 
 import (
+	"golang.org/x/tools/go/ssa"
 	"golang.org/x/tools/go/types"
 	"llvm.org/llvm/bindings/go/llvm"
 )
@@ -13,7 +14,7 @@ import (
 func (fr *frame) makeMap(typ types.Type, cap_ *govalue) *govalue {
 	// TODO(pcc): call __go_new_map_big here if needed
 	dyntyp := fr.types.getMapDescriptorPointer(typ)
-	dyntyp = fr.builder.CreateBitCast(dyntyp, llvm.PointerType(llvm.Int8Type(), 0), "")
+	dyntyp = fr.builder.CreateBitCast(dyntyp, llvm.PointerType(fr.llvmtypes.ctx.Int8Type(), 0), "")
 	var cap llvm.Value
 	if cap_ != nil {
 		cap = fr.convert(cap_, types.Typ[types.Uintptr]).value
@@ -29,13 +30,13 @@ func (fr *frame) mapLookup(m, k *govalue) (v *govalue, ok *govalue) {
 	llk := k.value
 	pk := fr.allocaBuilder.CreateAlloca(llk.Type(), "")
 	fr.builder.CreateStore(llk, pk)
-	valptr := fr.runtime.mapIndex.call(fr, m.value, pk, boolLLVMValue(false))[0]
+	valptr := fr.runtime.mapIndex.call(fr, m.value, pk, fr.boolLLVMValue(false))[0]
 	valptr.AddInstrAttribute(2, llvm.NoCaptureAttribute)
 	valptr.AddInstrAttribute(2, llvm.ReadOnlyAttribute)
 	okbit := fr.builder.CreateIsNotNull(valptr, "")
 
 	elemtyp := m.Type().Underlying().(*types.Map).Elem()
-	ok = newValue(fr.builder.CreateZExt(okbit, llvm.Int8Type(), ""), types.Typ[types.Bool])
+	ok = newValue(fr.builder.CreateZExt(okbit, fr.llvmtypes.ctx.Int8Type(), ""), types.Typ[types.Bool])
 	v = fr.loadOrNull(okbit, valptr, elemtyp)
 	return
 }
@@ -45,7 +46,7 @@ func (fr *frame) mapUpdate(m, k, v *govalue) {
 	llk := k.value
 	pk := fr.allocaBuilder.CreateAlloca(llk.Type(), "")
 	fr.builder.CreateStore(llk, pk)
-	valptr := fr.runtime.mapIndex.call(fr, m.value, pk, boolLLVMValue(true))[0]
+	valptr := fr.runtime.mapIndex.call(fr, m.value, pk, fr.boolLLVMValue(true))[0]
 	valptr.AddInstrAttribute(2, llvm.NoCaptureAttribute)
 	valptr.AddInstrAttribute(2, llvm.ReadOnlyAttribute)
 
@@ -55,6 +56,86 @@ func (fr *frame) mapUpdate(m, k, v *govalue) {
 	fr.builder.CreateStore(v.value, typedvalptr)
 }
 
+// minMapTableSize is the number of constant entries in a map literal in a
+// package initializer at or above which the entries are emitted as constant
+// data and inserted by a loop, rather than by one call per entry.
+const minMapTableSize = 16
+
+// constMapUpdates returns the updates with constant keys and values that
+// immediately follow the creation of the map m in instrs, where instrs[0]
+// is m. These are the entries of a map literal such as a lookup table.
+func constMapUpdates(m *ssa.MakeMap, instrs []ssa.Instruction) []*ssa.MapUpdate {
+	var updates []*ssa.MapUpdate
+	for _, instr := range instrs[1:] {
+		update, ok := instr.(*ssa.MapUpdate)
+		if !ok || update.Map != m {
+			break
+		}
+		if _, ok := update.Key.(*ssa.Const); !ok {
+			break
+		}
+		if _, ok := update.Value.(*ssa.Const); !ok {
+			break
+		}
+		updates = append(updates, update)
+	}
+	return updates
+}
+
+// mapUpdateTable implements m[k] = v for each of the given updates, whose
+// keys and values must be constants, by emitting the keys and values as
+// constant arrays and inserting them into the map in a loop.
+func (fr *frame) mapUpdateTable(m *govalue, updates []*ssa.MapUpdate) {
+	maptyp := m.Type().Underlying().(*types.Map)
+	keytyp := fr.types.ToLLVM(maptyp.Key())
+	elemtyp := fr.types.ToLLVM(maptyp.Elem())
+	keys := make([]llvm.Value, len(updates))
+	elems := make([]llvm.Value, len(updates))
+	for i, update := range updates {
+		keys[i] = fr.llvmvalue(update.Key)
+		elems[i] = fr.llvmvalue(update.Value)
+	}
+	keytable := fr.constTable(keytyp, keys)
+	elemtable := fr.constTable(elemtyp, elems)
+
+	entrybb := fr.builder.GetInsertBlock()
+	loopbb := fr.llvmtypes.ctx.AddBasicBlock(fr.function, "")
+	donebb := fr.llvmtypes.ctx.AddBasicBlock(fr.function, "")
+	fr.builder.CreateBr(loopbb)
+
+	fr.builder.SetInsertPointAtEnd(loopbb)
+	i := fr.builder.CreatePHI(fr.types.inttype, "")
+	zero := llvm.ConstNull(fr.types.inttype)
+	pk := fr.builder.CreateGEP(keytable, []llvm.Value{zero, i}, "")
+	valptr := fr.runtime.mapIndex.call(fr, m.value, pk, fr.boolLLVMValue(true))[0]
+	valptr.AddInstrAttribute(2, llvm.NoCaptureAttribute)
+	valptr.AddInstrAttribute(2, llvm.ReadOnlyAttribute)
+	typedvalptr := fr.builder.CreateBitCast(valptr, llvm.PointerType(elemtyp, 0), "")
+	elem := fr.builder.CreateLoad(fr.builder.CreateGEP(elemtable, []llvm.Value{zero, i}, ""), "")
+	fr.builder.CreateStore(elem, typedvalptr)
+
+	next := fr.builder.CreateAdd(i, llvm.ConstInt(fr.types.inttype, 1, false), "")
+	n := llvm.ConstInt(fr.types.inttype, uint64(len(updates)), false)
+	fr.builder.CreateCondBr(fr.builder.CreateICmp(llvm.IntULT, next, n, ""), loopbb, donebb)
+	i.AddIncoming(
+		[]llvm.Value{zero, next},
+		[]llvm.BasicBlock{entrybb, fr.builder.GetInsertBlock()},
+	)
+
+	fr.builder.SetInsertPointAtEnd(donebb)
+}
+
+// constTable returns an internal constant global array of type elemtyp
+// containing elems.
+func (fr *frame) constTable(elemtyp llvm.Type, elems []llvm.Value) llvm.Value {
+	init := llvm.ConstArray(elemtyp, elems)
+	table := llvm.AddGlobal(fr.module.Module, init.Type(), "")
+	table.SetLinkage(llvm.InternalLinkage)
+	table.SetGlobalConstant(true)
+	table.SetInitializer(init)
+	return table
+}
+
 // mapDelete implements delete(m, k)
 func (fr *frame) mapDelete(m, k *govalue) {
 	llk := k.value
@@ -69,8 +150,8 @@ func (fr *frame) mapIterInit(m *govalue) []*govalue {
 	// controls whether the code we generate for "next" (below) calls the
 	// runtime function for the first or the next element. We let the
 	// optimizer reorganize this into something more sensible.
-	isinit := fr.allocaBuilder.CreateAlloca(llvm.Int1Type(), "")
-	fr.builder.CreateStore(llvm.ConstNull(llvm.Int1Type()), isinit)
+	isinit := fr.allocaBuilder.CreateAlloca(fr.llvmtypes.ctx.Int1Type(), "")
+	fr.builder.CreateStore(llvm.ConstNull(fr.llvmtypes.ctx.Int1Type()), isinit)
 
 	return []*govalue{m, newValue(isinit, types.NewPointer(types.Typ[types.Bool]))}
 }
@@ -85,7 +166,7 @@ func (fr *frame) mapIterNext(iter []*govalue) []*govalue {
 
 	m, isinitptr := iter[0], iter[1]
 
-	i8ptr := llvm.PointerType(llvm.Int8Type(), 0)
+	i8ptr := llvm.PointerType(fr.llvmtypes.ctx.Int8Type(), 0)
 	mapiterbufty := llvm.ArrayType(i8ptr, 4)
 	mapiterbuf := fr.allocaBuilder.CreateAlloca(mapiterbufty, "")
 	mapiterbufelem0ptr := fr.builder.CreateStructGEP(mapiterbuf, 0, "")
@@ -97,14 +178,14 @@ func (fr *frame) mapIterNext(iter []*govalue) []*govalue {
 
 	isinit := fr.builder.CreateLoad(isinitptr.value, "")
 
-	initbb := llvm.AddBasicBlock(fr.function, "")
-	nextbb := llvm.AddBasicBlock(fr.function, "")
-	contbb := llvm.AddBasicBlock(fr.function, "")
+	initbb := fr.llvmtypes.ctx.AddBasicBlock(fr.function, "")
+	nextbb := fr.llvmtypes.ctx.AddBasicBlock(fr.function, "")
+	contbb := fr.llvmtypes.ctx.AddBasicBlock(fr.function, "")
 
 	fr.builder.CreateCondBr(isinit, nextbb, initbb)
 
 	fr.builder.SetInsertPointAtEnd(initbb)
-	fr.builder.CreateStore(llvm.ConstAllOnes(llvm.Int1Type()), isinitptr.value)
+	fr.builder.CreateStore(llvm.ConstAllOnes(fr.llvmtypes.ctx.Int1Type()), isinitptr.value)
 	fr.runtime.mapiterinit.call(fr, m.value, mapiterbufelem0ptr)
 	fr.builder.CreateBr(contbb)
 
@@ -115,10 +196,10 @@ func (fr *frame) mapIterNext(iter []*govalue) []*govalue {
 	fr.builder.SetInsertPointAtEnd(contbb)
 	mapiterbufelem0 := fr.builder.CreateLoad(mapiterbufelem0ptr, "")
 	okbit := fr.builder.CreateIsNotNull(mapiterbufelem0, "")
-	ok := fr.builder.CreateZExt(okbit, llvm.Int8Type(), "")
+	ok := fr.builder.CreateZExt(okbit, fr.llvmtypes.ctx.Int8Type(), "")
 
-	loadbb := llvm.AddBasicBlock(fr.function, "")
-	cont2bb := llvm.AddBasicBlock(fr.function, "")
+	loadbb := fr.llvmtypes.ctx.AddBasicBlock(fr.function, "")
+	cont2bb := fr.llvmtypes.ctx.AddBasicBlock(fr.function, "")
 	fr.builder.CreateCondBr(okbit, loadbb, cont2bb)
 
 	fr.builder.SetInsertPointAtEnd(loadbb)
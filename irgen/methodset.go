@@ -0,0 +1,79 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package irgen
+
+import (
+	"golang.org/x/tools/go/types"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+// MethodSets computes the method sets of types, and the order of the
+// methods in each, as laid out in interface method tables and type
+// descriptors. Method sets are computed according to the language
+// specification: the method set of a pointer type *T includes the methods
+// declared with receiver T, and embedded fields contribute their promoted
+// methods.
+//
+// Everything that depends on a method set (interface method calls and
+// conversions, type assertions, type descriptors and symbol mangling)
+// should use the same MethodSets, so that each agrees on the methods of
+// every type and the index of each method.
+type MethodSets struct {
+	cache   *types.MethodSetCache
+	ordered typeutil.Map
+}
+
+// NewMethodSets returns a MethodSets that computes method sets using
+// cache, which is typically the MethodSets field of an ssa.Program.
+func NewMethodSets(cache *types.MethodSetCache) *MethodSets {
+	return &MethodSets{cache: cache}
+}
+
+// MethodSet returns the method set of t.
+func (ms *MethodSets) MethodSet(t types.Type) *types.MethodSet {
+	return ms.cache.MethodSet(t)
+}
+
+// OrderedMethodSet returns the methods of the method set of t in the order
+// that gccgo uses: unexported methods first, then exported methods.
+func (ms *MethodSets) OrderedMethodSet(t types.Type) []*types.Selection {
+	if oms, ok := ms.ordered.At(t).([]*types.Selection); ok {
+		return oms
+	}
+	oms := orderedMethodSet(ms.MethodSet(t))
+	ms.ordered.Set(t, oms)
+	return oms
+}
+
+// MethodIndex returns the index of method in the ordered method set of t,
+// or -1 if method is not in that method set.
+func (ms *MethodSets) MethodIndex(t types.Type, method *types.Func) int {
+	for i, sel := range ms.OrderedMethodSet(t) {
+		if sel.Obj() == method {
+			return i
+		}
+	}
+	return -1
+}
+
+// orderedMethodSet assembles the method set into the order that gccgo
+// uses (unexported methods first).
+func orderedMethodSet(ms *types.MethodSet) []*types.Selection {
+	oms := make([]*types.Selection, ms.Len())
+	omsi := 0
+	for i := 0; i != ms.Len(); i++ {
+		if sel := ms.At(i); !sel.Obj().Exported() {
+			oms[omsi] = sel
+			omsi++
+		}
+	}
+	for i := 0; i != ms.Len(); i++ {
+		if sel := ms.At(i); sel.Obj().Exported() {
+			oms[omsi] = sel
+			omsi++
+		}
+	}
+	return oms
+}
@@ -0,0 +1,75 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package irgen
+
+import (
+	"bytes"
+	"debug/elf"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ExportDataSection is the name of the object file section that holds a
+// package's export data.
+const ExportDataSection = ".go_export"
+
+// ReadSections returns the contents of the sections named name in data,
+// which is either an ELF object file or an archive of them, in the order in
+// which they appear. Data that is neither an ELF object nor an archive, and
+// archive members that are not ELF objects, have no sections.
+func ReadSections(data []byte, name string) ([][]byte, error) {
+	const arMagic = "!<arch>\n"
+	if !bytes.HasPrefix(data, []byte(arMagic)) {
+		section, err := readObjectSection(data, name)
+		if section == nil || err != nil {
+			return nil, err
+		}
+		return [][]byte{section}, nil
+	}
+
+	var sections [][]byte
+	data = data[len(arMagic):]
+	for len(data) != 0 {
+		// Each member has a 60 byte header, ending with the member's
+		// size in decimal and "`\n", and is padded to an even size.
+		if len(data) < 60 || string(data[58:60]) != "`\n" {
+			return nil, fmt.Errorf("malformed archive")
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(string(data[48:58])))
+		if err != nil || size < 0 || size > len(data)-60 {
+			return nil, fmt.Errorf("malformed archive")
+		}
+		section, err := readObjectSection(data[60:60+size], name)
+		if err != nil {
+			return nil, err
+		}
+		if section != nil {
+			sections = append(sections, section)
+		}
+		size += size % 2
+		if size > len(data)-60 {
+			size = len(data) - 60
+		}
+		data = data[60+size:]
+	}
+	return sections, nil
+}
+
+// readObjectSection returns the contents of the section named name in the
+// object file data, or nil if it is not an ELF object or has no such
+// section.
+func readObjectSection(data []byte, name string) ([]byte, error) {
+	f, err := elf.NewFile(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil
+	}
+	defer f.Close()
+	section := f.Section(name)
+	if section == nil {
+		return nil, nil
+	}
+	return section.Data()
+}
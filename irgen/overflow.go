@@ -0,0 +1,78 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package irgen
+
+import (
+	"go/token"
+	"strconv"
+
+	"golang.org/x/tools/go/exact"
+	"golang.org/x/tools/go/types"
+	"llvm.org/llvm/bindings/go/llvm"
+)
+
+// isOverflowCheckedOp reports whether op applied to operands of type typ
+// is checked for overflow when the TrapOverflow option is set.
+func isOverflowCheckedOp(typ types.Type, op token.Token) bool {
+	if !isInteger(typ) || isUnsigned(typ) {
+		return false
+	}
+	switch op {
+	case token.ADD, token.SUB, token.MUL:
+		return true
+	}
+	return false
+}
+
+// checkedBinaryOp implements signed integer addition, subtraction and
+// multiplication using LLVM's overflow intrinsics, panicking if the
+// operation overflows.
+func (fr *frame) checkedBinaryOp(lhs *govalue, op token.Token, rhs *govalue) *govalue {
+	var name string
+	switch op {
+	case token.ADD:
+		name = "sadd"
+	case token.SUB:
+		name = "ssub"
+	case token.MUL:
+		name = "smul"
+	}
+	llty := lhs.value.Type()
+	name = "llvm." + name + ".with.overflow.i" + strconv.Itoa(llty.IntTypeWidth())
+	fn := fr.module.NamedFunction(name)
+	if fn.IsNil() {
+		rettyp := fr.llvmtypes.ctx.StructType([]llvm.Type{llty, fr.llvmtypes.ctx.Int1Type()}, false)
+		ftyp := llvm.FunctionType(rettyp, []llvm.Type{llty, llty}, false)
+		fn = llvm.AddFunction(fr.module.Module, name, ftyp)
+	}
+	result := fr.builder.CreateCall(fn, []llvm.Value{lhs.value, rhs.value}, "")
+	overflow := fr.builder.CreateExtractValue(result, 1, "")
+	fr.condBrPanic(overflow, "integer overflow")
+	return newValue(fr.builder.CreateExtractValue(result, 0, ""), lhs.typ)
+}
+
+// condBrPanic emits a conditional branch to a block that panics with the
+// given message, qualified by the position of the current instruction.
+// Unlike condBrRuntimeError, the panicking block is not shared, as each
+// has its own message.
+func (fr *frame) condBrPanic(cond llvm.Value, msg string) {
+	if cond.IsNull() {
+		return
+	}
+
+	errorbb := fr.llvmtypes.ctx.AddBasicBlock(fr.function, "")
+	contbb := fr.llvmtypes.ctx.AddBasicBlock(fr.function, "")
+	br := fr.builder.CreateCondBr(cond, errorbb, contbb)
+	fr.setBranchWeightMetadata(br, 1, 1000)
+
+	fr.builder.SetInsertPointAtEnd(errorbb)
+	if fr.pos.IsValid() {
+		msg += " at " + fr.pkg.Prog.Fset.Position(fr.pos).String()
+	}
+	str := fr.newValueFromConst(exact.MakeString(msg), types.Typ[types.String])
+	fr.callPanic(fr.makeInterface(str.value, str.Type(), types.NewInterface(nil, nil)))
+
+	fr.builder.SetInsertPointAtEnd(contbb)
+}
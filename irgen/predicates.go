@@ -49,3 +49,24 @@ func isSlice(typ types.Type, bkind types.BasicKind) bool {
 	t, ok := typ.Underlying().(*types.Slice)
 	return ok && types.Identical(t.Elem().Underlying(), types.Typ[bkind])
 }
+
+// isPointerType reports whether t is a pointer or unsafe.Pointer.
+func isPointerType(typ types.Type) bool {
+	switch t := typ.Underlying().(type) {
+	case *types.Pointer:
+		return true
+	case *types.Basic:
+		return t.Kind() == types.UnsafePointer
+	}
+	return false
+}
+
+func isUnsafePointer(typ types.Type) bool {
+	t, ok := typ.Underlying().(*types.Basic)
+	return ok && t.Kind() == types.UnsafePointer
+}
+
+func isSignature(typ types.Type) bool {
+	_, ok := typ.Underlying().(*types.Signature)
+	return ok
+}
@@ -0,0 +1,90 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package irgen
+
+import (
+	"bytes"
+	"go/ast"
+	"go/build"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+	"llvm.org/llvm/bindings/go/llvm"
+)
+
+// sourceImports returns the import paths of the packages transitively
+// imported by files that are to be compiled from source when compiling a
+// whole program: those found by ctx outside GOROOT. Packages in GOROOT,
+// and packages that ctx cannot find, are left to be imported from export
+// data. Each path is listed after the paths of the packages it imports.
+func sourceImports(ctx *build.Context, files []*ast.File, srcDir string) []string {
+	var paths []string
+	visited := make(map[string]bool)
+	var visit func(path, srcDir string)
+	visit = func(path, srcDir string) {
+		if path == "unsafe" || path == "C" || visited[path] {
+			return
+		}
+		visited[path] = true
+		bp, err := ctx.Import(path, srcDir, 0)
+		if err != nil || bp.Goroot {
+			return
+		}
+		for _, imp := range bp.Imports {
+			visit(imp, bp.Dir)
+		}
+		paths = append(paths, bp.ImportPath)
+	}
+	for _, file := range files {
+		for _, spec := range file.Imports {
+			path, err := strconv.Unquote(spec.Path.Value)
+			if err != nil {
+				continue
+			}
+			visit(path, srcDir)
+		}
+	}
+	return paths
+}
+
+// internalizePackages gives the definitions belonging to pkgs internal
+// linkage. This is only valid when compiling a whole program, as nothing
+// outside the module may then refer to them.
+func (c *compiler) internalizePackages(pkgs []*ssa.Package) {
+	if len(pkgs) == 0 {
+		return
+	}
+	prefixes := make([]string, len(pkgs))
+	for i, pkg := range pkgs {
+		var b bytes.Buffer
+		c.types.mc.manglePackagePath(pkg.Object.Path(), &b)
+		b.WriteByte('.')
+		prefixes[i] = b.String()
+	}
+	internalize := func(v llvm.Value) {
+		if v.Linkage() != llvm.ExternalLinkage {
+			return
+		}
+		name := v.Name()
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(name, prefix) {
+				v.SetLinkage(llvm.InternalLinkage)
+				return
+			}
+		}
+	}
+	m := c.module.Module
+	for fn := m.FirstFunction(); !fn.IsNil(); fn = llvm.NextFunction(fn) {
+		if fn.BasicBlocksCount() != 0 {
+			internalize(fn)
+		}
+	}
+	for g := m.FirstGlobal(); !g.IsNil(); g = llvm.NextGlobal(g) {
+		if !g.Initializer().IsNil() {
+			internalize(g)
+		}
+	}
+}
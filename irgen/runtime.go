@@ -36,7 +36,7 @@ func (rfi *runtimeFnInfo) callOnly(f *frame, args ...llvm.Value) []llvm.Value {
 }
 
 func (rfi *runtimeFnInfo) invoke(f *frame, lpad llvm.BasicBlock, args ...llvm.Value) []llvm.Value {
-	contbb := llvm.AddBasicBlock(f.function, "")
+	contbb := f.llvmtypes.ctx.AddBasicBlock(f.function, "")
 	return rfi.fi.invoke(f.llvmtypes.ctx, f.allocaBuilder, f.builder, rfi.fn, args, contbb, lpad)
 }
 
@@ -64,11 +64,14 @@ type runtimeInterface struct {
 	builtinClose,
 	convertInterface,
 	copy,
+	cstring,
 	Defer,
 	deferredRecover,
 	emptyInterfaceCompare,
 	getClosure,
 	Go,
+	gostring,
+	gostringn,
 	ifaceE2I2,
 	ifaceI2I2,
 	intArrayToString,
@@ -339,16 +342,6 @@ func newRuntimeInterface(module llvm.Module, tm *llvmTypeMap) (*runtimeInterface
 			rfi:  &ri.printBool,
 			args: []types.Type{Bool},
 		},
-		{
-			name: "__go_print_complex",
-			rfi:  &ri.printComplex,
-			args: []types.Type{Complex128},
-		},
-		{
-			name: "__go_print_double",
-			rfi:  &ri.printDouble,
-			args: []types.Type{Float64},
-		},
 		{
 			name: "__go_print_empty_interface",
 			rfi:  &ri.printEmptyInterface,
@@ -491,6 +484,36 @@ func newRuntimeInterface(module llvm.Module, tm *llvmTypeMap) (*runtimeInterface
 			rfi:  &ri.undefer,
 			args: []types.Type{UnsafePointer},
 		},
+		// The following are defined by llgo's own runtime library,
+		// libllgo, which is written in Go.
+		{
+			name: "__llgo_cstring",
+			rfi:  &ri.cstring,
+			args: []types.Type{String},
+			res:  []types.Type{UnsafePointer},
+		},
+		{
+			name: "__llgo_gostring",
+			rfi:  &ri.gostring,
+			args: []types.Type{UnsafePointer},
+			res:  []types.Type{String},
+		},
+		{
+			name: "__llgo_gostringn",
+			rfi:  &ri.gostringn,
+			args: []types.Type{UnsafePointer, Int},
+			res:  []types.Type{String},
+		},
+		{
+			name: "__llgo_print_complex",
+			rfi:  &ri.printComplex,
+			args: []types.Type{Complex128},
+		},
+		{
+			name: "__llgo_print_double",
+			rfi:  &ri.printDouble,
+			args: []types.Type{Float64},
+		},
 	} {
 		rt.rfi.init(tm, module, rt.name, rt.args, rt.res)
 		for _, attr := range rt.attrs {
@@ -498,57 +521,57 @@ func newRuntimeInterface(module llvm.Module, tm *llvmTypeMap) (*runtimeInterface
 		}
 	}
 
-	memsetName := "llvm.memset.p0i8.i" + strconv.Itoa(tm.target.IntPtrType().IntTypeWidth())
+	memsetName := "llvm.memset.p0i8.i" + strconv.Itoa(tm.inttype.IntTypeWidth())
 	memsetType := llvm.FunctionType(
-		llvm.VoidType(),
+		tm.ctx.VoidType(),
 		[]llvm.Type{
-			llvm.PointerType(llvm.Int8Type(), 0),
-			llvm.Int8Type(),
-			tm.target.IntPtrType(),
-			llvm.Int32Type(),
-			llvm.Int1Type(),
+			llvm.PointerType(tm.ctx.Int8Type(), 0),
+			tm.ctx.Int8Type(),
+			tm.inttype,
+			tm.ctx.Int32Type(),
+			tm.ctx.Int1Type(),
 		},
 		false,
 	)
 	ri.memset = llvm.AddFunction(module, memsetName, memsetType)
 
-	memcpyName := "llvm.memcpy.p0i8.p0i8.i" + strconv.Itoa(tm.target.IntPtrType().IntTypeWidth())
+	memcpyName := "llvm.memcpy.p0i8.p0i8.i" + strconv.Itoa(tm.inttype.IntTypeWidth())
 	memcpyType := llvm.FunctionType(
-		llvm.VoidType(),
+		tm.ctx.VoidType(),
 		[]llvm.Type{
-			llvm.PointerType(llvm.Int8Type(), 0),
-			llvm.PointerType(llvm.Int8Type(), 0),
-			tm.target.IntPtrType(),
-			llvm.Int32Type(),
-			llvm.Int1Type(),
+			llvm.PointerType(tm.ctx.Int8Type(), 0),
+			llvm.PointerType(tm.ctx.Int8Type(), 0),
+			tm.inttype,
+			tm.ctx.Int32Type(),
+			tm.ctx.Int1Type(),
 		},
 		false,
 	)
 	ri.memcpy = llvm.AddFunction(module, memcpyName, memcpyType)
 
 	returnaddressType := llvm.FunctionType(
-		llvm.PointerType(llvm.Int8Type(), 0),
-		[]llvm.Type{llvm.Int32Type()},
+		llvm.PointerType(tm.ctx.Int8Type(), 0),
+		[]llvm.Type{tm.ctx.Int32Type()},
 		false,
 	)
 	ri.returnaddress = llvm.AddFunction(module, "llvm.returnaddress", returnaddressType)
 
 	gccgoPersonalityType := llvm.FunctionType(
-		llvm.Int32Type(),
+		tm.ctx.Int32Type(),
 		[]llvm.Type{
-			llvm.Int32Type(),
-			llvm.Int64Type(),
-			llvm.PointerType(llvm.Int8Type(), 0),
-			llvm.PointerType(llvm.Int8Type(), 0),
+			tm.ctx.Int32Type(),
+			tm.ctx.Int64Type(),
+			llvm.PointerType(tm.ctx.Int8Type(), 0),
+			llvm.PointerType(tm.ctx.Int8Type(), 0),
 		},
 		false,
 	)
 	ri.gccgoPersonality = llvm.AddFunction(module, "__gccgo_personality_v0", gccgoPersonalityType)
 
-	ri.gccgoExceptionType = llvm.StructType(
+	ri.gccgoExceptionType = tm.ctx.StructType(
 		[]llvm.Type{
-			llvm.PointerType(llvm.Int8Type(), 0),
-			llvm.Int32Type(),
+			llvm.PointerType(tm.ctx.Int8Type(), 0),
+			tm.ctx.Int32Type(),
 		},
 		false,
 	)
@@ -559,9 +582,15 @@ func newRuntimeInterface(module llvm.Module, tm *llvmTypeMap) (*runtimeInterface
 func (fr *frame) createZExtOrTrunc(v llvm.Value, t llvm.Type, name string) llvm.Value {
 	switch n := v.Type().IntTypeWidth() - t.IntTypeWidth(); {
 	case n < 0:
-		v = fr.builder.CreateZExt(v, fr.target.IntPtrType(), name)
+		v = fr.builder.CreateZExt(v, fr.llvmtypes.inttype, name)
 	case n > 0:
-		v = fr.builder.CreateTrunc(v, fr.target.IntPtrType(), name)
+		orig := v
+		v = fr.builder.CreateTrunc(v, fr.llvmtypes.inttype, name)
+		if fr.TrapOverflow && orig.IsAConstant().IsNil() {
+			ext := fr.builder.CreateZExt(v, orig.Type(), "")
+			lossy := fr.builder.CreateICmp(llvm.IntNE, ext, orig, "")
+			fr.condBrPanic(lossy, "integer truncation")
+		}
 	}
 	return v
 }
@@ -574,37 +603,37 @@ func (fr *frame) createMalloc(size llvm.Value, hasPointers bool) llvm.Value {
 		allocator = &fr.runtime.NewNopointers
 	}
 
-	return allocator.callOnly(fr, fr.createZExtOrTrunc(size, fr.target.IntPtrType(), ""))[0]
+	return allocator.callOnly(fr, fr.createZExtOrTrunc(size, fr.llvmtypes.inttype, ""))[0]
 }
 
 func (fr *frame) createTypeMalloc(t types.Type) llvm.Value {
-	size := llvm.ConstInt(fr.target.IntPtrType(), uint64(fr.llvmtypes.Sizeof(t)), false)
+	size := llvm.ConstInt(fr.llvmtypes.inttype, uint64(fr.llvmtypes.Sizeof(t)), false)
 	malloc := fr.createMalloc(size, hasPointers(t))
 	return fr.builder.CreateBitCast(malloc, llvm.PointerType(fr.types.ToLLVM(t), 0), "")
 }
 
 func (fr *frame) memsetZero(ptr llvm.Value, size llvm.Value) {
 	memset := fr.runtime.memset
-	ptr = fr.builder.CreateBitCast(ptr, llvm.PointerType(llvm.Int8Type(), 0), "")
-	fill := llvm.ConstNull(llvm.Int8Type())
-	size = fr.createZExtOrTrunc(size, fr.target.IntPtrType(), "")
-	align := llvm.ConstInt(llvm.Int32Type(), 1, false)
-	isvolatile := llvm.ConstNull(llvm.Int1Type())
+	ptr = fr.builder.CreateBitCast(ptr, llvm.PointerType(fr.llvmtypes.ctx.Int8Type(), 0), "")
+	fill := llvm.ConstNull(fr.llvmtypes.ctx.Int8Type())
+	size = fr.createZExtOrTrunc(size, fr.llvmtypes.inttype, "")
+	align := llvm.ConstInt(fr.llvmtypes.ctx.Int32Type(), 1, false)
+	isvolatile := llvm.ConstNull(fr.llvmtypes.ctx.Int1Type())
 	fr.builder.CreateCall(memset, []llvm.Value{ptr, fill, size, align, isvolatile}, "")
 }
 
 func (fr *frame) memcpy(dest llvm.Value, src llvm.Value, size llvm.Value) {
 	memcpy := fr.runtime.memcpy
-	dest = fr.builder.CreateBitCast(dest, llvm.PointerType(llvm.Int8Type(), 0), "")
-	src = fr.builder.CreateBitCast(src, llvm.PointerType(llvm.Int8Type(), 0), "")
-	size = fr.createZExtOrTrunc(size, fr.target.IntPtrType(), "")
-	align := llvm.ConstInt(llvm.Int32Type(), 1, false)
-	isvolatile := llvm.ConstNull(llvm.Int1Type())
+	dest = fr.builder.CreateBitCast(dest, llvm.PointerType(fr.llvmtypes.ctx.Int8Type(), 0), "")
+	src = fr.builder.CreateBitCast(src, llvm.PointerType(fr.llvmtypes.ctx.Int8Type(), 0), "")
+	size = fr.createZExtOrTrunc(size, fr.llvmtypes.inttype, "")
+	align := llvm.ConstInt(fr.llvmtypes.ctx.Int32Type(), 1, false)
+	isvolatile := llvm.ConstNull(fr.llvmtypes.ctx.Int1Type())
 	fr.builder.CreateCall(memcpy, []llvm.Value{dest, src, size, align, isvolatile}, "")
 }
 
 func (fr *frame) returnAddress(level uint64) llvm.Value {
 	returnaddress := fr.runtime.returnaddress
-	levelValue := llvm.ConstInt(llvm.Int32Type(), level, false)
+	levelValue := llvm.ConstInt(fr.llvmtypes.ctx.Int32Type(), level, false)
 	return fr.builder.CreateCall(returnaddress, []llvm.Value{levelValue}, "")
 }
@@ -41,7 +41,7 @@ func (fr *frame) slice(x llvm.Value, xtyp types.Type, low, high, max llvm.Value)
 		arraytyp := typ.Elem().Underlying().(*types.Array)
 		elemtyp = arraytyp.Elem()
 		arrayptr = x
-		arrayptr = fr.builder.CreateBitCast(arrayptr, llvm.PointerType(llvm.Int8Type(), 0), "")
+		arrayptr = fr.builder.CreateBitCast(arrayptr, llvm.PointerType(fr.llvmtypes.ctx.Int8Type(), 0), "")
 		arraylen = llvm.ConstInt(fr.llvmtypes.inttype, uint64(arraytyp.Len()), false)
 		arraycap = arraylen
 	case *types.Slice:
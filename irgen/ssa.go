@@ -7,11 +7,14 @@ package irgen
 import (
 	"fmt"
 	"go/ast"
+	"go/scanner"
 	"go/token"
 	"os"
 	"sort"
+	"time"
 
 	"github.com/go-llvm/llgo/ssaopt"
+	"golang.org/x/tools/go/exact"
 	"golang.org/x/tools/go/ssa"
 	"golang.org/x/tools/go/ssa/ssautil"
 	"golang.org/x/tools/go/types"
@@ -76,7 +79,7 @@ func (gi *globalInit) build(typ llvm.Type) llvm.Value {
 		for i, eltyp := range eltypes {
 			elems[i] = gi.elems[i].build(eltyp)
 		}
-		return llvm.ConstStruct(elems, false)
+		return typ.Context().ConstStruct(elems, false)
 	case llvm.ArrayTypeKind:
 		eltyp := typ.ElementType()
 		elems := make([]llvm.Value, len(gi.elems))
@@ -95,6 +98,11 @@ type unit struct {
 	globals     map[ssa.Value]llvm.Value
 	globalInits map[llvm.Value]*globalInit
 
+	// pkgs contains the packages defined by this unit: pkg and, when
+	// compiling a whole program, the packages it imports from source,
+	// each after the packages it imports.
+	pkgs []*ssa.Package
+
 	// funcDescriptors maps *ssa.Functions to function descriptors,
 	// the first-class representation of functions.
 	funcDescriptors map[*ssa.Function]llvm.Value
@@ -103,17 +111,33 @@ type unit struct {
 	// (declared) but not defined.
 	undefinedFuncs map[*ssa.Function]bool
 
+	// bodylessFuncs caches the result of bodylessFunc for each function
+	// called.
+	bodylessFuncs map[*ssa.Function]bodylessFunc
+
 	gcRoots []llvm.Value
+
+	// errors contains the errors encountered while defining functions.
+	errors scanner.ErrorList
+
+	// codegenTimes records the time spent defining the functions of each
+	// source file. It is nil unless TimePhase is set.
+	codegenTimes map[string]time.Duration
 }
 
 func newUnit(c *compiler, pkg *ssa.Package) *unit {
 	u := &unit{
 		compiler:        c,
 		pkg:             pkg,
+		pkgs:            []*ssa.Package{pkg},
 		globals:         make(map[ssa.Value]llvm.Value),
 		globalInits:     make(map[llvm.Value]*globalInit),
 		funcDescriptors: make(map[*ssa.Function]llvm.Value),
 		undefinedFuncs:  make(map[*ssa.Function]bool),
+		bodylessFuncs:   make(map[*ssa.Function]bodylessFunc),
+	}
+	if c.TimePhase != nil {
+		u.codegenTimes = make(map[string]time.Duration)
 	}
 	return u
 }
@@ -147,13 +171,54 @@ func (u *unit) defineFunctionsInOrder(functions map[*ssa.Function]bool) {
 	}
 	sort.Sort(byFunctionString(fns))
 	for _, f := range fns {
+		start := time.Now()
 		u.defineFunction(f)
+		if u.codegenTimes != nil {
+			if file := u.fileset.Position(f.Pos()).Filename; file != "" {
+				u.codegenTimes[file] += time.Since(start)
+			}
+		}
+	}
+}
+
+// definesPackage reports whether pkg is one of the packages defined by u.
+func (u *unit) definesPackage(pkg *ssa.Package) bool {
+	for _, p := range u.pkgs {
+		if p == pkg {
+			return true
+		}
+	}
+	return false
+}
+
+// translatePackages translates the packages of the unit into its LLVM
+// module.
+func (u *unit) translatePackages() {
+	for _, pkg := range u.pkgs {
+		u.declarePackageMembers(pkg)
+	}
+
+	// Define functions.
+	u.defineFunctionsInOrder(ssautil.AllFunctions(u.pkg.Prog))
+
+	// Emit initializers for type descriptors, which may trigger
+	// the resolution of additional functions.
+	u.types.emitTypeDescInitializers()
+
+	// Define remaining functions that were resolved during
+	// runtime type mapping, but not defined.
+	u.defineFunctionsInOrder(u.undefinedFuncs)
+
+	// Set initializers for globals.
+	for global, init := range u.globalInits {
+		initval := init.build(global.Type().ElementType())
+		global.SetInitializer(initval)
 	}
 }
 
-// translatePackage translates an *ssa.Package into an LLVM module, and returns
-// the translation unit information.
-func (u *unit) translatePackage(pkg *ssa.Package) {
+// declarePackageMembers creates the global storage and type descriptors
+// for the members of pkg.
+func (u *unit) declarePackageMembers(pkg *ssa.Package) {
 	ms := make([]ssa.Member, len(pkg.Members))
 	i := 0
 	for _, m := range pkg.Members {
@@ -183,32 +248,15 @@ func (u *unit) translatePackage(pkg *ssa.Package) {
 			u.types.getTypeDescriptorPointer(v.Type())
 		}
 	}
-
-	// Define functions.
-	u.defineFunctionsInOrder(ssautil.AllFunctions(pkg.Prog))
-
-	// Emit initializers for type descriptors, which may trigger
-	// the resolution of additional functions.
-	u.types.emitTypeDescInitializers()
-
-	// Define remaining functions that were resolved during
-	// runtime type mapping, but not defined.
-	u.defineFunctionsInOrder(u.undefinedFuncs)
-
-	// Set initializers for globals.
-	for global, init := range u.globalInits {
-		initval := init.build(global.Type().ElementType())
-		global.SetInitializer(initval)
-	}
 }
 
 func (u *unit) addGlobal(global llvm.Value, ty types.Type) {
 	u.globalInits[global] = new(globalInit)
 
 	if hasPointers(ty) {
-		global = llvm.ConstBitCast(global, llvm.PointerType(llvm.Int8Type(), 0))
+		global = llvm.ConstBitCast(global, llvm.PointerType(u.llvmtypes.ctx.Int8Type(), 0))
 		size := llvm.ConstInt(u.types.inttype, uint64(u.types.Sizeof(ty)), false)
-		root := llvm.ConstStruct([]llvm.Value{global, size}, false)
+		root := u.llvmtypes.ctx.ConstStruct([]llvm.Value{global, size}, false)
 		u.gcRoots = append(u.gcRoots, root)
 	}
 }
@@ -217,7 +265,7 @@ func (u *unit) addGlobal(global llvm.Value, ty types.Type) {
 func (u *unit) ResolveMethod(s *types.Selection) *govalue {
 	m := u.pkg.Prog.Method(s)
 	llfn := u.resolveFunctionGlobal(m)
-	llfn = llvm.ConstBitCast(llfn, llvm.PointerType(llvm.Int8Type(), 0))
+	llfn = llvm.ConstBitCast(llfn, llvm.PointerType(u.llvmtypes.ctx.Int8Type(), 0))
 	return newValue(llfn, m.Signature)
 }
 
@@ -227,7 +275,7 @@ func (u *unit) resolveFunctionDescriptorGlobal(f *ssa.Function) llvm.Value {
 	llfd, ok := u.funcDescriptors[f]
 	if !ok {
 		name := u.types.mc.mangleFunctionName(f) + "$descriptor"
-		llfd = llvm.AddGlobal(u.module.Module, llvm.PointerType(llvm.Int8Type(), 0), name)
+		llfd = llvm.AddGlobal(u.module.Module, llvm.PointerType(u.llvmtypes.ctx.Int8Type(), 0), name)
 		llfd.SetGlobalConstant(true)
 		u.funcDescriptors[f] = llfd
 	}
@@ -238,7 +286,7 @@ func (u *unit) resolveFunctionDescriptorGlobal(f *ssa.Function) llvm.Value {
 // first-class value representation.
 func (u *unit) resolveFunctionDescriptor(f *ssa.Function) *govalue {
 	llfd := u.resolveFunctionDescriptorGlobal(f)
-	llfd = llvm.ConstBitCast(llfd, llvm.PointerType(llvm.Int8Type(), 0))
+	llfd = llvm.ConstBitCast(llfd, llvm.PointerType(u.llvmtypes.ctx.Int8Type(), 0))
 	return newValue(llfd, f.Signature)
 }
 
@@ -284,9 +332,9 @@ func (u *unit) getFunctionLinkage(f *ssa.Function) llvm.Linkage {
 }
 
 func (u *unit) defineFunction(f *ssa.Function) {
-	// Only define functions from this package, or synthetic
+	// Only define functions from this unit's packages, or synthetic
 	// wrappers (which do not have a package).
-	if f.Pkg != nil && f.Pkg != u.pkg {
+	if f.Pkg != nil && !u.definesPackage(f.Pkg) {
 		return
 	}
 
@@ -298,12 +346,18 @@ func (u *unit) defineFunction(f *ssa.Function) {
 	// Methods cannot be referred to via a descriptor.
 	if !isMethod {
 		llfd := u.resolveFunctionDescriptorGlobal(f)
-		llfd.SetInitializer(llvm.ConstBitCast(llfn, llvm.PointerType(llvm.Int8Type(), 0)))
+		llfd.SetInitializer(llvm.ConstBitCast(llfn, llvm.PointerType(u.llvmtypes.ctx.Int8Type(), 0)))
 		llfd.SetLinkage(linkage)
 	}
 
-	// We only need to emit a descriptor for functions without bodies.
+	// We only need to emit a descriptor for functions without bodies,
+	// unless the compiler provides the body. cgo's helpers are defined
+	// by the C code that cgo generates.
+	kind := u.bodylessFunc(f)
 	if len(f.Blocks) == 0 {
+		if _, cgo := cgoBodylessFuncs[f.Name()]; kind != notBodylessFunc && !cgo {
+			u.defineBodylessFunc(f, llfn, kind)
+		}
 		return
 	}
 
@@ -318,6 +372,29 @@ func (u *unit) defineFunction(f *ssa.Function) {
 	fr.addCommonFunctionAttrs(fr.function)
 	fr.function.SetLinkage(linkage)
 
+	// If code generation fails, record the error and give the function a
+	// body that traps, so that we may continue with the rest of the package
+	// and report all errors at once. Errors in the source, and constructs
+	// that llgo does not support, are reported by fr.errorf; any other
+	// panic is a bug in llgo, and is reported as an internal error.
+	defer func() {
+		if r := recover(); r != nil {
+			err, ok := r.(codegenError)
+			if !ok {
+				err = codegenError{fr.pos, fmt.Sprintf("internal compiler error: %v", r)}
+			}
+			pos := err.pos
+			if !pos.IsValid() {
+				pos = f.Pos()
+			}
+			u.errors.Add(u.pkg.Prog.Fset.Position(pos), fmt.Sprintf("%s: %s", f, err.msg))
+			stub := u.replaceWithTrap(fr.function)
+			if fr.function == llfn {
+				u.globals[f] = stub
+			}
+		}
+	}()
+
 	fr.logf("Define function: %s", f.String())
 	fti := u.llvmtypes.getSignatureInfo(f.Signature)
 	delete(u.undefinedFuncs, f)
@@ -337,14 +414,20 @@ func (u *unit) defineFunction(f *ssa.Function) {
 		fr = fr.bridgeRecoverFunc(fr.function, fti)
 	}
 
+	// Blocks only reachable through the untaken arm of an if with a constant
+	// condition are not translated, so that code guarded by a constant
+	// (such as a platform switch) need not refer to anything that exists.
+	live := liveBlocks(f)
 	fr.blocks = make([]llvm.BasicBlock, len(f.Blocks))
 	fr.lastBlocks = make([]llvm.BasicBlock, len(f.Blocks))
 	for i, block := range f.Blocks {
-		fr.blocks[i] = llvm.AddBasicBlock(fr.function, fmt.Sprintf(".%d.%s", i, block.Comment))
+		if live[i] {
+			fr.blocks[i] = u.llvmtypes.ctx.AddBasicBlock(fr.function, fmt.Sprintf(".%d.%s", i, block.Comment))
+		}
 	}
 	fr.builder.SetInsertPointAtEnd(fr.blocks[0])
 
-	prologueBlock := llvm.InsertBasicBlock(fr.blocks[0], "prologue")
+	prologueBlock := u.llvmtypes.ctx.InsertBasicBlock(fr.blocks[0], "prologue")
 	fr.builder.SetInsertPointAtEnd(prologueBlock)
 
 	// Map parameter positions to indices. We use this
@@ -353,7 +436,7 @@ func (u *unit) defineFunction(f *ssa.Function) {
 	paramPos := make(map[token.Pos]int)
 	for i, param := range f.Params {
 		paramPos[param.Pos()] = i
-		llparam := fti.argInfos[i].decode(llvm.GlobalContext(), fr.builder, fr.builder)
+		llparam := fti.argInfos[i].decode(u.llvmtypes.ctx, fr.builder, fr.builder)
 		if isMethod && i == 0 {
 			if _, ok := param.Type().Underlying().(*types.Pointer); !ok {
 				llparam = fr.builder.CreateBitCast(llparam, llvm.PointerType(fr.types.ToLLVM(param.Type()), 0), "")
@@ -369,11 +452,11 @@ func (u *unit) defineFunction(f *ssa.Function) {
 			fr.env[fv] = newValue(llvm.ConstNull(u.llvmtypes.ToLLVM(fv.Type())), fv.Type())
 		}
 		elemTypes := make([]llvm.Type, len(f.FreeVars)+1)
-		elemTypes[0] = llvm.PointerType(llvm.Int8Type(), 0) // function pointer
+		elemTypes[0] = llvm.PointerType(u.llvmtypes.ctx.Int8Type(), 0) // function pointer
 		for i, fv := range f.FreeVars {
 			elemTypes[i+1] = u.llvmtypes.ToLLVM(fv.Type())
 		}
-		structType := llvm.StructType(elemTypes, false)
+		structType := u.llvmtypes.ctx.StructType(elemTypes, false)
 		closure := fr.runtime.getClosure.call(fr)[0]
 		closure = fr.builder.CreateBitCast(closure, llvm.PointerType(structType, 0), "")
 		for i, fv := range f.FreeVars {
@@ -388,7 +471,7 @@ func (u *unit) defineFunction(f *ssa.Function) {
 		typ := fr.llvmtypes.ToLLVM(deref(local.Type()))
 		alloca := fr.builder.CreateAlloca(typ, local.Comment)
 		fr.memsetZero(alloca, llvm.SizeOf(typ))
-		bcalloca := fr.builder.CreateBitCast(alloca, llvm.PointerType(llvm.Int8Type(), 0), "")
+		bcalloca := fr.builder.CreateBitCast(alloca, llvm.PointerType(u.llvmtypes.ctx.Int8Type(), 0), "")
 		value := newValue(bcalloca, local.Type())
 		fr.env[local] = value
 		if fr.GenerateDebug {
@@ -409,15 +492,17 @@ func (u *unit) defineFunction(f *ssa.Function) {
 	// an unwind block. We can short-circuit the check for defers with
 	// f.Recover != nil.
 	if f.Recover != nil || hasDefer(f) {
-		fr.unwindBlock = llvm.AddBasicBlock(fr.function, "")
-		fr.frameptr = fr.builder.CreateAlloca(llvm.Int8Type(), "")
+		fr.unwindBlock = u.llvmtypes.ctx.AddBasicBlock(fr.function, "")
+		fr.frameptr = fr.builder.CreateAlloca(u.llvmtypes.ctx.Int8Type(), "")
 	}
 
 	term := fr.builder.CreateBr(fr.blocks[0])
 	fr.allocaBuilder.SetInsertPointBefore(term)
 
 	for _, block := range f.DomPreorder() {
-		fr.translateBlock(block, fr.blocks[block.Index])
+		if live[block.Index] {
+			fr.translateBlock(block, fr.blocks[block.Index])
+		}
 	}
 
 	fr.fixupPhis()
@@ -456,20 +541,56 @@ type frame struct {
 	phis                   []pendingPhi
 	canRecover             llvm.Value
 	isInit                 bool
+
+	// pos is the position of the instruction being translated,
+	// for use in diagnostics emitted by the generated code.
+	pos token.Pos
 }
 
 func newFrame(u *unit, fn llvm.Value) *frame {
 	return &frame{
 		unit:          u,
 		function:      fn,
-		builder:       llvm.GlobalContext().NewBuilder(),
-		allocaBuilder: llvm.GlobalContext().NewBuilder(),
+		builder:       u.llvmtypes.ctx.NewBuilder(),
+		allocaBuilder: u.llvmtypes.ctx.NewBuilder(),
 		env:           make(map[ssa.Value]*govalue),
 		ptr:           make(map[ssa.Value]llvm.Value),
 		tuples:        make(map[ssa.Value][]*govalue),
 	}
 }
 
+// replaceWithTrap replaces fn, which may be partially defined, with a
+// function of the same name and type whose body traps, and returns the
+// new function.
+func (u *unit) replaceWithTrap(fn llvm.Value) llvm.Value {
+	name := fn.Name()
+	fn.SetName("")
+	stub := llvm.AddFunction(u.module.Module, name, fn.Type().ElementType())
+	stub.SetLinkage(fn.Linkage())
+	u.addCommonFunctionAttrs(stub)
+	fn.ReplaceAllUsesWith(stub)
+	if u.PartialIRFile != "" {
+		// Keep the partially generated body for the dump of the module,
+		// marking it as the function whose code generation failed.
+		fn.SetName(name + PartialIRSuffix)
+		fn.SetLinkage(llvm.InternalLinkage)
+	} else {
+		fn.EraseFromParentAsFunction()
+	}
+
+	trap := u.module.NamedFunction("llvm.trap")
+	if trap.IsNil() {
+		trapType := llvm.FunctionType(u.llvmtypes.ctx.VoidType(), nil, false)
+		trap = llvm.AddFunction(u.module.Module, "llvm.trap", trapType)
+	}
+	builder := u.llvmtypes.ctx.NewBuilder()
+	defer builder.Dispose()
+	builder.SetInsertPointAtEnd(u.llvmtypes.ctx.AddBasicBlock(stub, "entry"))
+	builder.CreateCall(trap, nil, "")
+	builder.CreateUnreachable()
+	return stub
+}
+
 func (fr *frame) dispose() {
 	fr.builder.Dispose()
 	fr.allocaBuilder.Dispose()
@@ -490,7 +611,7 @@ func (fr *frame) bridgeRecoverFunc(llfn llvm.Value, fti functionTypeInfo) *frame
 	llfn.AddFunctionAttr(llvm.NoInlineAttribute)
 
 	// Call __go_can_recover, passing in the function's return address.
-	entry := llvm.AddBasicBlock(llfn, "entry")
+	entry := fr.llvmtypes.ctx.AddBasicBlock(llfn, "entry")
 	fr.builder.SetInsertPointAtEnd(entry)
 	canRecover := fr.runtime.canRecover.call(fr, fr.returnAddress(0))[0]
 	returnType := fti.functionType.ReturnType()
@@ -528,23 +649,27 @@ func (fr *frame) registerGcRoots() {
 		rootty := fr.gcRoots[0].Type()
 		roots := append(fr.gcRoots, llvm.ConstNull(rootty))
 		rootsarr := llvm.ConstArray(rootty, roots)
-		rootsstruct := llvm.ConstStruct([]llvm.Value{llvm.ConstNull(llvm.PointerType(llvm.Int8Type(), 0)), rootsarr}, false)
+		rootsstruct := fr.llvmtypes.ctx.ConstStruct([]llvm.Value{llvm.ConstNull(llvm.PointerType(fr.llvmtypes.ctx.Int8Type(), 0)), rootsarr}, false)
 
 		rootsglobal := llvm.AddGlobal(fr.module.Module, rootsstruct.Type(), "")
 		rootsglobal.SetInitializer(rootsstruct)
 		rootsglobal.SetLinkage(llvm.InternalLinkage)
-		fr.runtime.registerGcRoots.callOnly(fr, llvm.ConstBitCast(rootsglobal, llvm.PointerType(llvm.Int8Type(), 0)))
+		fr.runtime.registerGcRoots.callOnly(fr, llvm.ConstBitCast(rootsglobal, llvm.PointerType(fr.llvmtypes.ctx.Int8Type(), 0)))
 	}
 }
 
 func (fr *frame) fixupPhis() {
 	for _, phi := range fr.phis {
-		values := make([]llvm.Value, len(phi.ssa.Edges))
-		blocks := make([]llvm.BasicBlock, len(phi.ssa.Edges))
+		var values []llvm.Value
+		var blocks []llvm.BasicBlock
 		block := phi.ssa.Block()
 		for i, edge := range phi.ssa.Edges {
-			values[i] = fr.llvmvalue(edge)
-			blocks[i] = fr.lastBlock(block.Preds[i])
+			pred := block.Preds[i]
+			if fr.blocks[pred.Index].IsNil() || !branchesTo(pred, block) {
+				continue
+			}
+			values = append(values, fr.llvmvalue(edge))
+			blocks = append(blocks, fr.lastBlock(pred))
 		}
 		phi.llvm.AddIncoming(values, blocks)
 	}
@@ -555,17 +680,17 @@ func (fr *frame) createLandingPad(cleanup bool) llvm.Value {
 	if cleanup {
 		lp.SetCleanup(true)
 	} else {
-		lp.AddClause(llvm.ConstNull(llvm.PointerType(llvm.Int8Type(), 0)))
+		lp.AddClause(llvm.ConstNull(llvm.PointerType(fr.llvmtypes.ctx.Int8Type(), 0)))
 	}
 	return lp
 }
 
 // Runs defers. If a defer panics, check for recovers in later defers.
 func (fr *frame) runDefers() {
-	loopbb := llvm.AddBasicBlock(fr.function, "")
+	loopbb := fr.llvmtypes.ctx.AddBasicBlock(fr.function, "")
 	fr.builder.CreateBr(loopbb)
 
-	retrylpad := llvm.AddBasicBlock(fr.function, "")
+	retrylpad := fr.llvmtypes.ctx.AddBasicBlock(fr.function, "")
 	fr.builder.SetInsertPointAtEnd(retrylpad)
 	fr.createLandingPad(false)
 	fr.runtime.checkDefer.callOnly(fr, fr.frameptr)
@@ -576,7 +701,7 @@ func (fr *frame) runDefers() {
 }
 
 func (fr *frame) setupUnwindBlock(rec *ssa.BasicBlock, results *types.Tuple) {
-	recoverbb := llvm.AddBasicBlock(fr.function, "")
+	recoverbb := fr.llvmtypes.ctx.AddBasicBlock(fr.function, "")
 	if rec != nil {
 		fr.translateBlock(rec, recoverbb)
 	} else if results.Len() == 0 || results.At(0).Anonymous() {
@@ -586,13 +711,13 @@ func (fr *frame) setupUnwindBlock(rec *ssa.BasicBlock, results *types.Tuple) {
 		for i := range values {
 			values[i] = llvm.ConstNull(fr.llvmtypes.ToLLVM(results.At(i).Type()))
 		}
-		fr.retInf.encode(llvm.GlobalContext(), fr.allocaBuilder, fr.builder, values)
+		fr.retInf.encode(fr.llvmtypes.ctx, fr.allocaBuilder, fr.builder, values)
 	} else {
 		fr.builder.SetInsertPointAtEnd(recoverbb)
 		fr.builder.CreateUnreachable()
 	}
 
-	checkunwindbb := llvm.AddBasicBlock(fr.function, "")
+	checkunwindbb := fr.llvmtypes.ctx.AddBasicBlock(fr.function, "")
 	fr.builder.SetInsertPointAtEnd(checkunwindbb)
 	exc := fr.createLandingPad(true)
 	fr.runDefers()
@@ -600,7 +725,7 @@ func (fr *frame) setupUnwindBlock(rec *ssa.BasicBlock, results *types.Tuple) {
 	frame := fr.builder.CreateLoad(fr.frameptr, "")
 	shouldresume := fr.builder.CreateIsNull(frame, "")
 
-	resumebb := llvm.AddBasicBlock(fr.function, "")
+	resumebb := fr.llvmtypes.ctx.AddBasicBlock(fr.function, "")
 	fr.builder.CreateCondBr(shouldresume, resumebb, recoverbb)
 
 	fr.builder.SetInsertPointAtEnd(resumebb)
@@ -615,12 +740,53 @@ func (fr *frame) setupUnwindBlock(rec *ssa.BasicBlock, results *types.Tuple) {
 
 func (fr *frame) translateBlock(b *ssa.BasicBlock, llb llvm.BasicBlock) {
 	fr.builder.SetInsertPointAtEnd(llb)
-	for _, instr := range b.Instrs {
+	var phis []ssa.Value
+	for i := 0; i < len(b.Instrs); i++ {
+		instr := b.Instrs[i]
+		if fr.StackRoots {
+			// Phis must stay at the start of the block, so their roots
+			// are stored before the first instruction that follows them.
+			if _, ok := instr.(*ssa.Phi); !ok {
+				for _, phi := range phis {
+					fr.addStackRoots(phi)
+				}
+				phis = nil
+			}
+		}
 		fr.instruction(instr)
+		if v, ok := instr.(ssa.Value); ok && fr.StackRoots {
+			if _, ok := v.(*ssa.Phi); ok {
+				phis = append(phis, v)
+			} else {
+				fr.addStackRoots(v)
+			}
+		}
+
+		// Insert the constant entries of large map literals in package
+		// initializers from tables, rather than one at a time.
+		if m, ok := instr.(*ssa.MakeMap); ok && fr.isInit {
+			updates := constMapUpdates(m, b.Instrs[i:])
+			if len(updates) >= minMapTableSize {
+				fr.mapUpdateTable(fr.value(m), updates)
+				i += len(updates)
+			}
+		}
 	}
 	fr.lastBlocks[b.Index] = fr.builder.GetInsertBlock()
 }
 
+// ensureBlockOpen starts a fresh basic block if the current insertion
+// block has already been terminated, for example by a call to a noreturn
+// function such as panic. Any code subsequently emitted into the new block
+// is dead, but the resulting IR remains valid.
+func (fr *frame) ensureBlockOpen() {
+	last := fr.builder.GetInsertBlock().LastInstruction()
+	if !last.IsNil() && !last.IsATerminatorInst().IsNil() {
+		deadbb := fr.llvmtypes.ctx.AddBasicBlock(fr.function, "")
+		fr.builder.SetInsertPointAtEnd(deadbb)
+	}
+}
+
 func (fr *frame) block(b *ssa.BasicBlock) llvm.BasicBlock {
 	return fr.blocks[b.Index]
 }
@@ -642,7 +808,7 @@ func (fr *frame) value(v ssa.Value) (result *govalue) {
 			return newValue(g, v.Type())
 		}
 		// Create an external global. Globals for this package are defined
-		// on entry to translatePackage, and have initialisers.
+		// on entry to translatePackages, and have initialisers.
 		llelemtyp := fr.llvmtypes.ToLLVM(deref(v.Type()))
 		vname := fr.types.mc.mangleGlobalName(v)
 		llglobal := llvm.AddGlobal(fr.module.Module, llelemtyp, vname)
@@ -671,7 +837,7 @@ func (fr *frame) isNonNull(v ssa.Value) bool {
 		// Globals have a fixed (non-nil) address.
 		*ssa.Global,
 		// The language does not specify what happens if an allocation fails.
-		*ssa.Alloc,
+		*ssa.Alloc, *ssa.MakeClosure,
 		// These have already been nil checked.
 		*ssa.FieldAddr, *ssa.IndexAddr:
 		return true
@@ -737,6 +903,10 @@ func (fr *frame) canAvoidLoad(instr *ssa.UnOp, op llvm.Value) bool {
 	return true
 }
 
+// largeHeapAllocSize is the size in bytes at or above which a heap allocation
+// made by an alloc instruction causes a warning.
+const largeHeapAllocSize = 1 << 16
+
 // Return true iff we think it might be beneficial to turn this alloc instruction
 // into a statically allocated global.
 // Precondition: we are compiling the init function.
@@ -802,8 +972,44 @@ func (fr *frame) maybeStoreInInitializer(val, addr llvm.Value) bool {
 	}
 }
 
+// convertPointerArith recognises conversions of the form
+// unsafe.Pointer(uintptr(p) +/- offset), and translates them into a GEP
+// on p rather than a ptrtoint/inttoptr round-trip. The optimizer would
+// otherwise be free to assume that the result of the inttoptr does not
+// alias the object that p points into.
+func (fr *frame) convertPointerArith(instr *ssa.Convert) (*govalue, bool) {
+	if instr.Type().Underlying() != types.Typ[types.UnsafePointer] {
+		return nil, false
+	}
+	binop, ok := instr.X.(*ssa.BinOp)
+	if !ok || (binop.Op != token.ADD && binop.Op != token.SUB) {
+		return nil, false
+	}
+	base, offset := binop.X, binop.Y
+	conv, ok := base.(*ssa.Convert)
+	if !ok && binop.Op == token.ADD {
+		// offset + uintptr(p)
+		base, offset = offset, base
+		conv, ok = base.(*ssa.Convert)
+	}
+	if !ok || conv.X.Type().Underlying() != types.Typ[types.UnsafePointer] {
+		return nil, false
+	}
+
+	ptr := fr.llvmvalue(conv.X)
+	lloffset := fr.llvmvalue(offset)
+	if binop.Op == token.SUB {
+		lloffset = fr.builder.CreateNeg(lloffset, "")
+	}
+	ptr = fr.builder.CreateGEP(ptr, []llvm.Value{lloffset}, "")
+	return newValue(ptr, instr.Type()), true
+}
+
 func (fr *frame) instruction(instr ssa.Instruction) {
 	fr.logf("[%T] %v @ %s\n", instr, instr, fr.pkg.Prog.Fset.Position(instr.Pos()))
+	if pos := instr.Pos(); pos.IsValid() {
+		fr.pos = pos
+	}
 	if fr.GenerateDebug {
 		fr.debug.SetLocation(fr.builder, instr.Pos())
 	}
@@ -824,18 +1030,25 @@ func (fr *frame) instruction(instr ssa.Instruction) {
 			global := llvm.AddGlobal(fr.module.Module, llvmtyp, "")
 			global.SetLinkage(llvm.InternalLinkage)
 			fr.addGlobal(global, typ)
-			ptr := llvm.ConstBitCast(global, llvm.PointerType(llvm.Int8Type(), 0))
+			ptr := llvm.ConstBitCast(global, llvm.PointerType(fr.llvmtypes.ctx.Int8Type(), 0))
 			fr.env[instr] = newValue(ptr, instr.Type())
 		} else {
+			if size := fr.types.Sizeof(typ); size >= largeHeapAllocSize {
+				fr.warnf(fr.pos, "heap-allocating %d byte value of type %s", size, typ)
+			}
 			value = fr.createTypeMalloc(typ)
 			value.SetName(instr.Comment)
-			value = fr.builder.CreateBitCast(value, llvm.PointerType(llvm.Int8Type(), 0), "")
+			value = fr.builder.CreateBitCast(value, llvm.PointerType(fr.llvmtypes.ctx.Int8Type(), 0), "")
 			fr.env[instr] = newValue(value, instr.Type())
 		}
 
 	case *ssa.BinOp:
 		lhs, rhs := fr.value(instr.X), fr.value(instr.Y)
-		fr.env[instr] = fr.binaryOp(lhs, instr.Op, rhs)
+		if fr.TrapOverflow && isOverflowCheckedOp(lhs.Type(), instr.Op) {
+			fr.env[instr] = fr.checkedBinaryOp(lhs, instr.Op, rhs)
+		} else {
+			fr.env[instr] = fr.binaryOp(lhs, instr.Op, rhs)
+		}
 
 	case *ssa.Call:
 		tuple := fr.callInstruction(instr)
@@ -864,7 +1077,15 @@ func (fr *frame) instruction(instr ssa.Instruction) {
 		fr.env[instr] = newValue(value, instr.Type())
 
 	case *ssa.Convert:
+		if v, ok := fr.convertPointerArith(instr); ok {
+			fr.env[instr] = v
+			break
+		}
 		v := fr.value(instr.X)
+		if isTemporaryString(instr) {
+			fr.env[instr] = fr.byteSliceToString(v, false)
+			break
+		}
 		fr.env[instr] = fr.convert(v, instr.Type())
 
 	case *ssa.Defer:
@@ -904,7 +1125,7 @@ func (fr *frame) instruction(instr ssa.Instruction) {
 		ptrtyp := llvm.PointerType(fr.llvmtypes.ToLLVM(xtyp), 0)
 		ptr = fr.builder.CreateBitCast(ptr, ptrtyp, "")
 		fieldptr := fr.builder.CreateStructGEP(ptr, instr.Field, instr.Name())
-		fieldptr = fr.builder.CreateBitCast(fieldptr, llvm.PointerType(llvm.Int8Type(), 0), "")
+		fieldptr = fr.builder.CreateBitCast(fieldptr, llvm.PointerType(fr.llvmtypes.ctx.Int8Type(), 0), "")
 		fieldptrtyp := instr.Type()
 		fr.env[instr] = newValue(fieldptr, fieldptrtyp)
 
@@ -913,11 +1134,15 @@ func (fr *frame) instruction(instr ssa.Instruction) {
 		fr.runtime.Go.call(fr, fn, arg)
 
 	case *ssa.If:
-		cond := fr.llvmvalue(instr.Cond)
 		block := instr.Block()
+		if succ, ok := constIfSuccessor(block); ok {
+			fr.builder.CreateBr(fr.block(succ))
+			break
+		}
+		cond := fr.llvmvalue(instr.Cond)
 		trueBlock := fr.block(block.Succs[0])
 		falseBlock := fr.block(block.Succs[1])
-		cond = fr.builder.CreateTrunc(cond, llvm.Int1Type(), "")
+		cond = fr.builder.CreateTrunc(cond, fr.llvmtypes.ctx.Int1Type(), "")
 		fr.builder.CreateCondBr(cond, trueBlock, falseBlock)
 
 	case *ssa.Index:
@@ -993,7 +1218,7 @@ func (fr *frame) instruction(instr ssa.Instruction) {
 		ptrtyp := llvm.PointerType(fr.llvmtypes.ToLLVM(elemtyp), 0)
 		arrayptr = fr.builder.CreateBitCast(arrayptr, ptrtyp, "")
 		addr := fr.builder.CreateGEP(arrayptr, []llvm.Value{index}, "")
-		addr = fr.builder.CreateBitCast(addr, llvm.PointerType(llvm.Int8Type(), 0), "")
+		addr = fr.builder.CreateBitCast(addr, llvm.PointerType(fr.llvmtypes.ctx.Int8Type(), 0), "")
 		fr.env[instr] = newValue(addr, types.NewPointer(elemtyp))
 
 	case *ssa.Jump:
@@ -1019,7 +1244,7 @@ func (fr *frame) instruction(instr ssa.Instruction) {
 
 	case *ssa.MakeClosure:
 		llfn := fr.resolveFunctionGlobal(instr.Fn.(*ssa.Function))
-		llfn = llvm.ConstBitCast(llfn, llvm.PointerType(llvm.Int8Type(), 0))
+		llfn = llvm.ConstBitCast(llfn, llvm.PointerType(fr.llvmtypes.ctx.Int8Type(), 0))
 		fn := newValue(llfn, instr.Fn.(*ssa.Function).Signature)
 		bindings := make([]*govalue, len(instr.Bindings))
 		for i, binding := range instr.Bindings {
@@ -1076,7 +1301,7 @@ func (fr *frame) instruction(instr ssa.Instruction) {
 		case *types.Basic: // string
 			fr.tuples[instr] = fr.stringIterInit(x)
 		default:
-			panic(fmt.Sprintf("unhandled range for type %T", x.Type()))
+			fr.errorf(instr.Pos(), "range over %s is not supported", x.Type())
 		}
 
 	case *ssa.Return:
@@ -1084,7 +1309,7 @@ func (fr *frame) instruction(instr ssa.Instruction) {
 		for i, res := range instr.Results {
 			vals[i] = fr.llvmvalue(res)
 		}
-		fr.retInf.encode(llvm.GlobalContext(), fr.allocaBuilder, fr.builder, vals)
+		fr.retInf.encode(fr.llvmtypes.ctx, fr.allocaBuilder, fr.builder, vals)
 
 	case *ssa.RunDefers:
 		fr.runDefers()
@@ -1156,7 +1381,7 @@ func (fr *frame) instruction(instr ssa.Instruction) {
 		}
 
 	default:
-		panic(fmt.Sprintf("unhandled: %v", instr))
+		fr.errorf(fr.pos, "unsupported instruction: %v", instr)
 	}
 }
 
@@ -1217,8 +1442,9 @@ func (fr *frame) callBuiltin(typ types.Type, builtin *ssa.Builtin, args []ssa.Va
 		return []*govalue{ptr}
 
 	default:
-		panic("unimplemented: " + builtin.Name())
+		fr.errorf(fr.pos, "unsupported builtin function %s", builtin.Name())
 	}
+	panic("unreachable")
 }
 
 // callInstruction translates function call instructions.
@@ -1244,14 +1470,23 @@ func (fr *frame) callInstruction(instr ssa.CallInstruction) []*govalue {
 		args = append([]*govalue{recv}, args...)
 	} else {
 		if ssafn, ok := call.Value.(*ssa.Function); ok {
+			if kind := fr.bodylessFunc(ssafn); kind != notBodylessFunc {
+				return fr.callBodylessFunc(ssafn, kind, call, args)
+			}
+			if results, ok := fr.callIntrinsic(ssafn, args); ok {
+				return results
+			}
 			llfn := fr.resolveFunctionGlobal(ssafn)
-			llfn = llvm.ConstBitCast(llfn, llvm.PointerType(llvm.Int8Type(), 0))
+			llfn = llvm.ConstBitCast(llfn, llvm.PointerType(fr.llvmtypes.ctx.Int8Type(), 0))
 			fn = newValue(llfn, ssafn.Type())
 		} else {
 			// First-class function values are stored as *{*fnptr}, so
 			// we must extract the function pointer. We must also
 			// call __go_set_closure, in case the function is a closure.
+			// Calling a nil function value panics, as does a nil
+			// dereference.
 			fn = fr.value(call.Value)
+			fr.nilCheck(call.Value, fn.value)
 			fr.runtime.setClosure.call(fr, fn.value)
 			fnptr := fr.builder.CreateBitCast(fn.value, llvm.PointerType(fn.value.Type(), 0), "")
 			fnptr = fr.builder.CreateLoad(fnptr, "")
@@ -1259,8 +1494,15 @@ func (fr *frame) callInstruction(instr ssa.CallInstruction) []*govalue {
 		}
 		if recv := call.Signature().Recv(); recv != nil {
 			if _, ok := recv.Type().Underlying().(*types.Pointer); !ok {
+				// Value receivers are passed by pointer to a copy, which
+				// the callee may not modify.
 				recvalloca := fr.allocaBuilder.CreateAlloca(args[0].value.Type(), "")
-				fr.builder.CreateStore(args[0].value, recvalloca)
+				if src, ok := fr.loadedReceiver(call.Args[0], instr); ok {
+					size := llvm.ConstInt(fr.llvmtypes.inttype, uint64(fr.llvmtypes.Sizeof(args[0].Type())), false)
+					fr.memcpy(recvalloca, src, size)
+				} else {
+					fr.builder.CreateStore(args[0].value, recvalloca)
+				}
 				args[0] = newValue(recvalloca, types.NewPointer(args[0].Type()))
 			}
 		}
@@ -1268,6 +1510,116 @@ func (fr *frame) callInstruction(instr ssa.CallInstruction) []*govalue {
 	return fr.createCall(fn, args)
 }
 
+// largeReceiverSize is the size in bytes above which a value receiver that
+// was loaded from memory is copied with memcpy, rather than by storing the
+// loaded value.
+const largeReceiverSize = 64
+
+// loadedReceiver reports whether recv, a large value receiver for the call
+// instr, was loaded from memory by the instruction immediately preceding
+// instr, and if so returns the address it was loaded from. As there are no
+// intervening instructions, the memory still holds the receiver's value.
+func (fr *frame) loadedReceiver(recv ssa.Value, instr ssa.CallInstruction) (llvm.Value, bool) {
+	load, ok := recv.(*ssa.UnOp)
+	if !ok || load.Op != token.MUL || load.Block() != instr.Block() {
+		return llvm.Value{}, false
+	}
+	if fr.llvmtypes.Sizeof(recv.Type()) <= largeReceiverSize {
+		return llvm.Value{}, false
+	}
+	instrs := instr.Block().Instrs
+	for i, in := range instrs {
+		if in == instr {
+			if i > 0 && instrs[i-1] == load {
+				return fr.llvmvalue(load.X), true
+			}
+			break
+		}
+	}
+	return llvm.Value{}, false
+}
+
+// liveBlocks returns, for each block of f, whether it is reachable from the
+// entry or recover block, disregarding the untaken successors of ifs with
+// constant conditions.
+func liveBlocks(f *ssa.Function) []bool {
+	live := make([]bool, len(f.Blocks))
+	var visit func(b *ssa.BasicBlock)
+	visit = func(b *ssa.BasicBlock) {
+		if live[b.Index] {
+			return
+		}
+		live[b.Index] = true
+		if succ, ok := constIfSuccessor(b); ok {
+			visit(succ)
+			return
+		}
+		for _, succ := range b.Succs {
+			visit(succ)
+		}
+	}
+	if len(f.Blocks) != 0 {
+		visit(f.Blocks[0])
+	}
+	if f.Recover != nil {
+		visit(f.Recover)
+	}
+	return live
+}
+
+// constIfSuccessor returns the successor of b that is always taken, if b
+// ends with an if whose condition is a constant.
+func constIfSuccessor(b *ssa.BasicBlock) (*ssa.BasicBlock, bool) {
+	if len(b.Instrs) == 0 {
+		return nil, false
+	}
+	instr, ok := b.Instrs[len(b.Instrs)-1].(*ssa.If)
+	if !ok {
+		return nil, false
+	}
+	cond, ok := constCond(instr.Cond)
+	if !ok {
+		return nil, false
+	}
+	if cond {
+		return b.Succs[0], true
+	}
+	return b.Succs[1], true
+}
+
+// constCond returns the value of the boolean cond if it is a constant, or
+// a comparison of constants, such as those generated for the cases of a
+// switch on a constant.
+func constCond(cond ssa.Value) (bool, bool) {
+	switch cond := cond.(type) {
+	case *ssa.Const:
+		return exact.BoolVal(cond.Value), true
+	case *ssa.BinOp:
+		x, ok := cond.X.(*ssa.Const)
+		if !ok || x.Value == nil {
+			return false, false
+		}
+		y, ok := cond.Y.(*ssa.Const)
+		if !ok || y.Value == nil {
+			return false, false
+		}
+		switch cond.Op {
+		case token.EQL, token.NEQ, token.LSS, token.LEQ, token.GTR, token.GEQ:
+			return exact.Compare(x.Value, cond.Op, y.Value), true
+		}
+	}
+	return false, false
+}
+
+// branchesTo reports whether the code generated for pred may branch to
+// succ, which is a successor of pred in the SSA control flow graph.
+func branchesTo(pred, succ *ssa.BasicBlock) bool {
+	if taken, ok := constIfSuccessor(pred); ok {
+		return taken == succ
+	}
+	return true
+}
+
 func hasDefer(f *ssa.Function) bool {
 	for _, b := range f.Blocks {
 		for _, instr := range b.Instrs {
@@ -0,0 +1,50 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package irgen
+
+import (
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/types"
+	"llvm.org/llvm/bindings/go/llvm"
+)
+
+// addStackRoots stores the value of v, or each element of a tuple value,
+// that may contain pointers to a stack slot of its own. This is used with
+// CompilerOptions.StackRoots so that libgo's collector, which scans stacks
+// conservatively, finds the value even if the optimizer would otherwise
+// keep it only in a register.
+func (fr *frame) addStackRoots(v ssa.Value) {
+	// Nothing follows a call to a function that does not return.
+	last := fr.builder.GetInsertBlock().LastInstruction()
+	if !last.IsNil() && !last.IsATerminatorInst().IsNil() {
+		return
+	}
+	if tuple, ok := v.Type().(*types.Tuple); ok {
+		for i, elem := range fr.tuples[v] {
+			if elem != nil && hasPointers(tuple.At(i).Type()) {
+				fr.addStackRoot(elem.value)
+			}
+		}
+		return
+	}
+	if gv, ok := fr.env[v]; ok && hasPointers(v.Type()) {
+		fr.addStackRoot(gv.value)
+	}
+}
+
+// addStackRoot allocates a zeroed slot for v in the entry block and stores
+// v to it at the current insertion point. The slot's address is passed to
+// an empty inline assembly statement that clobbers memory, so that the
+// slot is considered to escape: it is neither promoted to a register nor
+// are stores to it removed as dead.
+func (fr *frame) addStackRoot(v llvm.Value) {
+	i8ptr := llvm.PointerType(fr.llvmtypes.ctx.Int8Type(), 0)
+	slot := fr.allocaBuilder.CreateAlloca(v.Type(), "")
+	fr.allocaBuilder.CreateStore(llvm.ConstNull(v.Type()), slot)
+	escapeType := llvm.FunctionType(fr.llvmtypes.ctx.VoidType(), []llvm.Type{i8ptr}, false)
+	escape := llvm.InlineAsm(escapeType, "", "r,~{memory}", true, false)
+	fr.allocaBuilder.CreateCall(escape, []llvm.Value{fr.allocaBuilder.CreateBitCast(slot, i8ptr, "")}, "")
+	fr.builder.CreateStore(v, slot)
+}
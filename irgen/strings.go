@@ -6,6 +6,8 @@ package irgen
 
 import (
 	"go/token"
+
+	"golang.org/x/tools/go/ssa"
 	"golang.org/x/tools/go/types"
 	"llvm.org/llvm/bindings/go/llvm"
 )
@@ -15,6 +17,93 @@ func (fr *frame) concatenateStrings(lhs, rhs *govalue) *govalue {
 	return newValue(result[0], types.Typ[types.String])
 }
 
+// byteSliceToString implements string(v), where v is a byte slice. If
+// copyData is false, the resulting string shares its data with v.
+func (fr *frame) byteSliceToString(v *govalue, copyData bool) *govalue {
+	data := fr.builder.CreateExtractValue(v.value, 0, "")
+	len := fr.builder.CreateExtractValue(v.value, 1, "")
+
+	if copyData {
+		// Data must be copied, to prevent changes in
+		// the byte slice from mutating the string.
+		newdata := fr.createMalloc(len, false)
+		fr.memcpy(newdata, data, len)
+		data = newdata
+	}
+
+	struct_ := llvm.Undef(fr.types.ToLLVM(types.Typ[types.String]))
+	struct_ = fr.builder.CreateInsertValue(struct_, data, 0, "")
+	struct_ = fr.builder.CreateInsertValue(struct_, len, 1, "")
+	return newValue(struct_, types.Typ[types.String])
+}
+
+// isTemporaryString reports whether conv, a conversion from a byte slice
+// to a string, is used only as an operand of string comparisons or as the
+// key of map lookups that follow it in the same block, with nothing in
+// between that could modify the byte slice. None of these uses retain the
+// string, so it may share the data of the byte slice rather than copying
+// it, as in m[string(b)] or string(b) == "x".
+func isTemporaryString(conv *ssa.Convert) bool {
+	if !isSlice(conv.X.Type().Underlying(), types.Byte) || !isString(conv.Type().Underlying()) {
+		return false
+	}
+
+	refs := *conv.Referrers()
+	if len(refs) == 0 {
+		return false
+	}
+	uses := make(map[ssa.Instruction]bool)
+	for _, ref := range refs {
+		switch ref := ref.(type) {
+		case *ssa.BinOp:
+			switch ref.Op {
+			case token.EQL, token.NEQ, token.LSS, token.LEQ, token.GTR, token.GEQ:
+			default:
+				return false
+			}
+		case *ssa.Lookup:
+			if _, ok := ref.X.Type().Underlying().(*types.Map); !ok || ref.Index != conv {
+				return false
+			}
+		case *ssa.DebugRef:
+			continue
+		default:
+			return false
+		}
+		if ref.Block() != conv.Block() {
+			return false
+		}
+		uses[ref] = true
+	}
+
+	instrs := conv.Block().Instrs
+	i := 0
+	for instrs[i] != conv {
+		i++
+	}
+	for _, instr := range instrs[i+1:] {
+		if len(uses) == 0 {
+			return true
+		}
+		if uses[instr] {
+			delete(uses, instr)
+			continue
+		}
+		switch instr := instr.(type) {
+		case *ssa.BinOp, *ssa.ChangeType, *ssa.Convert, *ssa.DebugRef,
+			*ssa.Extract, *ssa.Field, *ssa.FieldAddr, *ssa.Index,
+			*ssa.IndexAddr, *ssa.Lookup, *ssa.Slice:
+		case *ssa.UnOp:
+			if instr.Op == token.ARROW {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return len(uses) == 0
+}
+
 func (fr *frame) compareStrings(lhs, rhs *govalue, op token.Token) *govalue {
 	result := fr.runtime.strcmp.call(fr, lhs.value, rhs.value)[0]
 	zero := llvm.ConstNull(fr.types.inttype)
@@ -36,14 +125,26 @@ func (fr *frame) compareStrings(lhs, rhs *govalue, op token.Token) *govalue {
 		panic("unreachable")
 	}
 	result = fr.builder.CreateICmp(pred, result, zero, "")
-	result = fr.builder.CreateZExt(result, llvm.Int8Type(), "")
+	result = fr.builder.CreateZExt(result, fr.llvmtypes.ctx.Int8Type(), "")
 	return newValue(result, types.Typ[types.Bool])
 }
 
 // stringIndex implements v = m[i]
 func (fr *frame) stringIndex(s, i *govalue) *govalue {
 	ptr := fr.builder.CreateExtractValue(s.value, 0, "")
-	ptr = fr.builder.CreateGEP(ptr, []llvm.Value{i.value}, "")
+	length := fr.builder.CreateExtractValue(s.value, 1, "")
+
+	// The index may not have been promoted to int.
+	index := fr.createZExtOrTrunc(i.value, fr.types.inttype, "")
+
+	// Bounds checking: 0 <= index < len
+	zero := llvm.ConstNull(fr.types.inttype)
+	i0 := fr.builder.CreateICmp(llvm.IntSLT, index, zero, "")
+	li := fr.builder.CreateICmp(llvm.IntSLE, length, index, "")
+	cond := fr.builder.CreateOr(i0, li, "")
+	fr.condBrRuntimeError(cond, gccgoRuntimeErrorSTRING_INDEX_OUT_OF_BOUNDS)
+
+	ptr = fr.builder.CreateGEP(ptr, []llvm.Value{index}, "")
 	return newValue(fr.builder.CreateLoad(ptr, ""), types.Typ[types.Byte])
 }
 
@@ -61,7 +162,7 @@ func (fr *frame) stringIterNext(iter []*govalue) []*govalue {
 	result := fr.runtime.stringiter2.call(fr, str.value, k)
 	fr.builder.CreateStore(result[0], indexptr.value)
 	ok := fr.builder.CreateIsNotNull(result[0], "")
-	ok = fr.builder.CreateZExt(ok, llvm.Int8Type(), "")
+	ok = fr.builder.CreateZExt(ok, fr.llvmtypes.ctx.Int8Type(), "")
 	v := result[1]
 
 	return []*govalue{newValue(ok, types.Typ[types.Bool]), newValue(k, types.Typ[types.Int]), newValue(v, types.Typ[types.Rune])}
@@ -0,0 +1,155 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package irgen
+
+import (
+	"fmt"
+	"go/ast"
+	"go/scanner"
+	"go/token"
+
+	"golang.org/x/tools/go/exact"
+	"golang.org/x/tools/go/types"
+)
+
+// switchChecker checks the cases of switch statements, which the type
+// checker does not fully validate: duplicate constant cases in expression
+// switches, duplicate types in type switches, and case expressions that
+// cannot be compared with the switch tag.
+type switchChecker struct {
+	fset   *token.FileSet
+	info   *types.Info
+	errors scanner.ErrorList
+}
+
+// checkSwitches checks the switch statements in files, whose types are
+// recorded in info, returning the errors found as a scanner.ErrorList.
+func checkSwitches(fset *token.FileSet, files []*ast.File, info *types.Info) scanner.ErrorList {
+	sc := switchChecker{fset: fset, info: info}
+	for _, file := range files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.SwitchStmt:
+				sc.exprSwitch(n)
+			case *ast.TypeSwitchStmt:
+				sc.typeSwitch(n)
+			}
+			return true
+		})
+	}
+	return sc.errors
+}
+
+func (sc *switchChecker) errorf(pos token.Pos, format string, args ...interface{}) {
+	sc.errors.Add(sc.fset.Position(pos), fmt.Sprintf(format, args...))
+}
+
+// isNil reports whether e is the predeclared identifier nil.
+func (sc *switchChecker) isNil(e ast.Expr) bool {
+	id, ok := e.(*ast.Ident)
+	if !ok || id.Name != "nil" {
+		return false
+	}
+	obj := sc.info.ObjectOf(id)
+	return obj == nil || obj == types.Universe.Lookup("nil")
+}
+
+// exprSwitch checks the cases of an expression switch. As with gc, only
+// duplicate integer, floating-point and string constants are rejected.
+func (sc *switchChecker) exprSwitch(s *ast.SwitchStmt) {
+	var tag types.Type
+	if s.Tag != nil {
+		if tag = sc.info.TypeOf(s.Tag); tag == nil {
+			return
+		}
+	}
+	type caseValue struct {
+		val exact.Value
+		typ types.Type
+		pos token.Pos
+	}
+	var seen []caseValue
+	for _, stmt := range s.Body.List {
+		clause, ok := stmt.(*ast.CaseClause)
+		if !ok {
+			continue
+		}
+		for _, e := range clause.List {
+			tv, ok := sc.info.Types[e]
+			if !ok || tv.Type == nil {
+				continue
+			}
+			if tag != nil {
+				if sc.isNil(e) {
+					if !hasNilValue(tag) {
+						sc.errorf(e.Pos(), "invalid case nil in switch on %s (mismatched types nil and %s)", types.ExprString(s.Tag), tag)
+					}
+					continue
+				}
+				for _, t := range []types.Type{tag, tv.Type} {
+					if !types.Comparable(t) {
+						sc.errorf(e.Pos(), "invalid case %s in switch on %s (%s cannot be compared)", types.ExprString(e), types.ExprString(s.Tag), t)
+						break
+					}
+				}
+			}
+			switch {
+			case tv.Value == nil:
+				continue
+			case tv.Value.Kind() != exact.Int && tv.Value.Kind() != exact.Float && tv.Value.Kind() != exact.String:
+				continue
+			}
+			for _, prev := range seen {
+				if types.Identical(prev.typ, tv.Type) && exact.Compare(prev.val, token.EQL, tv.Value) {
+					sc.errorf(e.Pos(), "duplicate case %s in switch (previous case at %s)", types.ExprString(e), sc.fset.Position(prev.pos))
+					break
+				}
+			}
+			seen = append(seen, caseValue{tv.Value, tv.Type, e.Pos()})
+		}
+	}
+}
+
+// typeSwitch checks that no type, or nil, appears in more than one case of
+// a type switch.
+func (sc *switchChecker) typeSwitch(s *ast.TypeSwitchStmt) {
+	type caseType struct {
+		typ types.Type // nil for "case nil"
+		pos token.Pos
+	}
+	var seen []caseType
+	for _, stmt := range s.Body.List {
+		clause, ok := stmt.(*ast.CaseClause)
+		if !ok {
+			continue
+		}
+		for _, e := range clause.List {
+			var typ types.Type
+			if tv, ok := sc.info.Types[e]; ok && tv.IsType() {
+				typ = tv.Type
+			} else if !sc.isNil(e) {
+				continue
+			}
+			for _, prev := range seen {
+				if prev.typ == typ || prev.typ != nil && typ != nil && types.Identical(prev.typ, typ) {
+					sc.errorf(e.Pos(), "duplicate case %s in type switch (previous case at %s)", types.ExprString(e), sc.fset.Position(prev.pos))
+					break
+				}
+			}
+			seen = append(seen, caseType{typ, e.Pos()})
+		}
+	}
+}
+
+// hasNilValue reports whether nil may be compared with values of type t.
+func hasNilValue(t types.Type) bool {
+	switch t := t.Underlying().(type) {
+	case *types.Basic:
+		return t.Kind() == types.UnsafePointer
+	case *types.Pointer, *types.Slice, *types.Map, *types.Chan, *types.Signature, *types.Interface:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,177 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package irgen
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"golang.org/x/tools/go/types"
+)
+
+// checkMangledPathCollisions reports an error if pkg, or any package it
+// imports, has a path whose mangled form is shared by another package.
+// Such packages would define identically named symbols, and so could not
+// be linked into the same program.
+func (c *compiler) checkMangledPathCollisions(pkg *types.Package) error {
+	seen := make(map[string]*types.Package)
+	visited := make(map[*types.Package]bool)
+	var visit func(p *types.Package) error
+	visit = func(p *types.Package) error {
+		if visited[p] {
+			return nil
+		}
+		visited[p] = true
+		var b bytes.Buffer
+		c.types.mc.manglePackagePath(p.Path(), &b)
+		if other, ok := seen[b.String()]; ok && other.Path() != p.Path() {
+			return fmt.Errorf("packages %q and %q would define colliding symbols with prefix %q", other.Path(), p.Path(), b.String()+".")
+		}
+		seen[b.String()] = p
+		for _, imp := range p.Imports() {
+			if err := visit(imp); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return visit(pkg)
+}
+
+// SymbolKind is the kind of declaration, or of compiler-generated code or
+// data, that a symbol emitted by llgo corresponds to.
+type SymbolKind int
+
+const (
+	// CSymbol is a symbol that does not follow llgo's mangling scheme,
+	// such as a C function.
+	CSymbol SymbolKind = iota
+
+	// RuntimeSymbol is a symbol defined by libgo or libllgo.
+	RuntimeSymbol
+
+	// TypeInfoSymbol is a type descriptor, map descriptor or interface
+	// method table.
+	TypeInfoSymbol
+
+	// TypeAlgSymbol is a type hash or equality function.
+	TypeAlgSymbol
+
+	// PackageInitSymbol is a package's initialization function.
+	PackageInitSymbol
+
+	// FuncLitSymbol is a function literal.
+	FuncLitSymbol
+
+	// MethodSymbol is a method.
+	MethodSymbol
+
+	// DeclSymbol is a package-level function or variable.
+	DeclSymbol
+)
+
+// A Symbol is the demangled form of the name of a symbol emitted by llgo.
+type Symbol struct {
+	Kind SymbolKind
+
+	// Package is the mangled path of the package that the symbol belongs
+	// to, or "" for C and runtime symbols, and for the type information
+	// of unnamed and predeclared types.
+	Package string
+
+	// Name is the name of the function literal, method or declaration
+	// within Package. For other kinds of symbol, it is the symbol's name.
+	// Any $descriptor or $recover suffix is removed.
+	Name string
+
+	// Recv is the mangled receiver type of a method.
+	Recv string
+
+	// Descriptor reports whether the symbol is the function descriptor of
+	// the function it names, and Recover whether it is its recover thunk.
+	Descriptor, Recover bool
+}
+
+// ParseSymbol demangles the name of a symbol emitted by llgo.
+func ParseSymbol(name string) Symbol {
+	var sym Symbol
+	if strings.HasSuffix(name, "$descriptor") {
+		sym.Descriptor = true
+		name = name[:len(name)-len("$descriptor")]
+	}
+	if strings.HasSuffix(name, "$recover") {
+		sym.Recover = true
+		name = name[:len(name)-len("$recover")]
+	}
+	sym.Name = name
+	switch {
+	case strings.HasPrefix(name, "__go_tdn_"):
+		sym.Kind = TypeInfoSymbol
+		if i := strings.Index(name, "."); i > 0 {
+			sym.Package = name[len("__go_tdn_"):i]
+		}
+		return sym
+	case strings.HasPrefix(name, "__go_td_") || strings.HasPrefix(name, "__go_map_") ||
+		strings.HasPrefix(name, "__go_imt_"):
+		sym.Kind = TypeInfoSymbol
+		return sym
+	case strings.HasPrefix(name, "__go_type_"):
+		sym.Kind = TypeAlgSymbol
+		return sym
+	case strings.HasPrefix(name, "__go_") || strings.HasPrefix(name, "__llgo_") ||
+		strings.HasPrefix(name, "runtime_"):
+		sym.Kind = RuntimeSymbol
+		return sym
+	}
+	if i := strings.Index(name, ":"); i > 0 {
+		sym.Kind = FuncLitSymbol
+		sym.Name = name[i+1:]
+		if j := strings.Index(name[:i], "."); j > 0 {
+			sym.Package = name[:j]
+		}
+		return sym
+	}
+	if i := strings.Index(name, ".."); i > 0 {
+		sym.Kind = PackageInitSymbol
+		sym.Package = name[:i]
+		return sym
+	}
+	i := strings.Index(name, ".")
+	if i <= 0 {
+		sym.Kind = CSymbol
+		return sym
+	}
+	sym.Package, sym.Name = name[:i], name[i+1:]
+	sym.Kind = DeclSymbol
+	if j := strings.Index(sym.Name, "."); j > 0 {
+		sym.Kind = MethodSymbol
+		sym.Name, sym.Recv = sym.Name[:j], sym.Name[j+1:]
+	}
+	return sym
+}
+
+// DescribeSymbol returns a human readable description of the Go declaration
+// that a symbol emitted by llgo corresponds to, for use in diagnostics.
+// Package paths cannot be fully recovered from their mangled forms, so
+// the description uses the mangled path.
+func DescribeSymbol(name string) string {
+	sym := ParseSymbol(name)
+	switch sym.Kind {
+	case TypeInfoSymbol, TypeAlgSymbol:
+		return "type information symbol " + sym.Name
+	case RuntimeSymbol:
+		return "runtime symbol " + sym.Name
+	case FuncLitSymbol:
+		return "function literal " + sym.Name
+	case PackageInitSymbol:
+		return fmt.Sprintf("package %s (initialization)", sym.Package)
+	case MethodSymbol:
+		return fmt.Sprintf("method %s of type %s in package %s", sym.Name, sym.Recv, sym.Package)
+	case DeclSymbol:
+		return fmt.Sprintf("%s in package %s", sym.Name, sym.Package)
+	}
+	return "C symbol " + sym.Name
+}
@@ -36,7 +36,16 @@ type llvmTypeMap struct {
 	inttype    llvm.Type
 	stringType llvm.Type
 
-	types typeutil.Map
+	// hasher memoizes type hashes for this and the other typeutil.Maps
+	// keyed by types in the unit.
+	hasher typeutil.Hasher
+
+	// types maps types, up to identity, to their LLVM types. typeCache
+	// maps each types.Type value already seen directly to its LLVM type,
+	// so that identical types (which are frequently the very same value)
+	// need not be hashed and compared again on every conversion.
+	types     typeutil.Map
+	typeCache map[types.Type]llvm.Type
 }
 
 type typeDescInfo struct {
@@ -53,11 +62,11 @@ type TypeMap struct {
 	mc manglerContext
 
 	module         llvm.Module
-	pkgpath        string
+	pkgpaths       map[string]bool
 	types, algs    typeutil.Map
 	runtime        *runtimeInterface
 	methodResolver MethodResolver
-	types.MethodSetCache
+	*MethodSets
 
 	commonTypeType, uncommonTypeType, ptrTypeType, funcTypeType, arrayTypeType, sliceTypeType, mapTypeType, chanTypeType, interfaceTypeType, structTypeType llvm.Type
 	mapDescType                                                                                                                                             llvm.Type
@@ -80,11 +89,11 @@ func NewLLVMTypeMap(ctx llvm.Context, target llvm.TargetData) *llvmTypeMap {
 	// ABI currently requires sizeof(int) == sizeof(uint) == sizeof(uintptr).
 	inttype := ctx.IntType(8 * target.PointerSize())
 
-	i8ptr := llvm.PointerType(llvm.Int8Type(), 0)
+	i8ptr := llvm.PointerType(ctx.Int8Type(), 0)
 	elements := []llvm.Type{i8ptr, inttype}
-	stringType := llvm.StructType(elements, false)
+	stringType := ctx.StructType(elements, false)
 
-	return &llvmTypeMap{
+	tm := &llvmTypeMap{
 		ctx: ctx,
 		sizes: &types.StdSizes{
 			WordSize: int64(target.PointerSize()),
@@ -93,23 +102,30 @@ func NewLLVMTypeMap(ctx llvm.Context, target llvm.TargetData) *llvmTypeMap {
 		target:     target,
 		inttype:    inttype,
 		stringType: stringType,
+		hasher:     typeutil.MakeHasher(),
+		typeCache:  make(map[types.Type]llvm.Type),
 	}
+	tm.types.SetHasher(tm.hasher)
+	return tm
 }
 
 func NewTypeMap(pkg *ssa.Package, llvmtm *llvmTypeMap, module llvm.Module, r *runtimeInterface, mr MethodResolver) *TypeMap {
 	tm := &TypeMap{
 		llvmTypeMap:    llvmtm,
 		module:         module,
-		pkgpath:        pkg.Object.Path(),
+		pkgpaths:       map[string]bool{pkg.Object.Path(): true},
 		runtime:        r,
 		methodResolver: mr,
 	}
 
-	tm.mc.init(pkg.Prog, &tm.MethodSetCache)
+	tm.types.SetHasher(llvmtm.hasher)
+	tm.algs.SetHasher(llvmtm.hasher)
+	tm.MethodSets = NewMethodSets(&pkg.Prog.MethodSets)
+	tm.mc.init(pkg.Prog, tm.MethodSets)
 
 	uintptrType := tm.inttype
 	voidPtrType := llvm.PointerType(tm.ctx.Int8Type(), 0)
-	boolType := llvm.Int8Type()
+	boolType := tm.ctx.Int8Type()
 	stringPtrType := llvm.PointerType(tm.stringType, 0)
 
 	// Create runtime algorithm function types.
@@ -270,6 +286,9 @@ func (tm *llvmTypeMap) ToLLVM(t types.Type) llvm.Type {
 }
 
 func (tm *llvmTypeMap) toLLVM(t types.Type, name string) llvm.Type {
+	if lt, ok := tm.typeCache[t]; ok {
+		return lt
+	}
 	lt, ok := tm.types.At(t).(llvm.Type)
 	if !ok {
 		lt = tm.makeLLVMType(t, name)
@@ -278,6 +297,7 @@ func (tm *llvmTypeMap) toLLVM(t types.Type, name string) llvm.Type {
 		}
 		tm.types.Set(t, lt)
 	}
+	tm.typeCache[t] = lt
 	return lt
 }
 
@@ -348,14 +368,43 @@ func (tm *llvmTypeMap) Sizeof(T types.Type) int64 {
 	return tm.sizes.WordSize // catch-all
 }
 
+// Alignof returns the alignment of t, which for basic types is given by the
+// target data layout rather than by the size of the type: on some 32-bit
+// targets (such as i386), 64-bit integers and floats are only 4-byte
+// aligned. This must agree with the layout LLVM gives the type's LLVM type.
 func (tm *llvmTypeMap) Alignof(t types.Type) int64 {
-	return tm.sizes.Alignof(t)
+	switch t := t.Underlying().(type) {
+	case *types.Array:
+		return tm.Alignof(t.Elem())
+	case *types.Struct:
+		max := int64(1)
+		for i := 0; i != t.NumFields(); i++ {
+			if a := tm.Alignof(t.Field(i).Type()); a > max {
+				max = a
+			}
+		}
+		return max
+	case *types.Basic:
+		switch k := t.Kind(); k {
+		case types.Float32, types.Complex64:
+			return int64(tm.target.ABITypeAlignment(tm.ctx.FloatType()))
+		case types.Float64, types.Complex128:
+			return int64(tm.target.ABITypeAlignment(tm.ctx.DoubleType()))
+		case types.String, types.UnsafePointer:
+		default:
+			if int(k) < len(basicSizes) && basicSizes[k] > 0 {
+				return int64(tm.target.ABITypeAlignment(tm.ctx.IntType(8 * int(basicSizes[k]))))
+			}
+		}
+	}
+	// Everything else is, or begins with, a pointer or a word-sized integer.
+	return int64(tm.target.ABITypeAlignment(tm.inttype))
 }
 
 ///////////////////////////////////////////////////////////////////////////////
 
 func (tm *TypeMap) ToRuntime(t types.Type) llvm.Value {
-	return llvm.ConstBitCast(tm.getTypeDescriptorPointer(t), llvm.PointerType(llvm.Int8Type(), 0))
+	return llvm.ConstBitCast(tm.getTypeDescriptorPointer(t), llvm.PointerType(tm.ctx.Int8Type(), 0))
 }
 
 type localNamedTypeInfo struct {
@@ -370,32 +419,12 @@ type namedTypeInfo struct {
 }
 
 type manglerContext struct {
-	ti  map[*types.Named]localNamedTypeInfo
-	msc *types.MethodSetCache
-}
-
-// Assembles the method set into the order that gccgo uses (unexported methods first).
-// TODO(pcc): cache this.
-func orderedMethodSet(ms *types.MethodSet) []*types.Selection {
-	oms := make([]*types.Selection, ms.Len())
-	omsi := 0
-	for i := 0; i != ms.Len(); i++ {
-		if sel := ms.At(i); !sel.Obj().Exported() {
-			oms[omsi] = sel
-			omsi++
-		}
-	}
-	for i := 0; i != ms.Len(); i++ {
-		if sel := ms.At(i); sel.Obj().Exported() {
-			oms[omsi] = sel
-			omsi++
-		}
-	}
-	return oms
+	ti map[*types.Named]localNamedTypeInfo
+	ms *MethodSets
 }
 
-func (ctx *manglerContext) init(prog *ssa.Program, msc *types.MethodSetCache) {
-	ctx.msc = msc
+func (ctx *manglerContext) init(prog *ssa.Program, ms *MethodSets) {
+	ctx.ms = ms
 	ctx.ti = make(map[*types.Named]localNamedTypeInfo)
 	for f, _ := range ssautil.AllFunctions(prog) {
 		scopeNum := 0
@@ -567,8 +596,7 @@ func (ctx *manglerContext) mangleType(t types.Type, b *bytes.Buffer) {
 
 	case *types.Interface:
 		b.WriteRune('I')
-		methodset := ctx.msc.MethodSet(t)
-		for _, m := range orderedMethodSet(methodset) {
+		for _, m := range ctx.ms.OrderedMethodSet(t) {
 			method := m.Obj()
 			var nb bytes.Buffer
 			if !method.Exported() {
@@ -788,7 +816,7 @@ func (tm *TypeMap) getTypeHash(t types.Type) uint32 {
 
 	case *types.Interface:
 		var h uint32
-		for _, m := range orderedMethodSet(tm.MethodSet(t)) {
+		for _, m := range tm.OrderedMethodSet(t) {
 			h = getStringHash(m.Obj().Name(), h)
 			h <<= 1
 		}
@@ -999,10 +1027,17 @@ func (tm *TypeMap) getTypeDescType(t types.Type) llvm.Type {
 	}
 }
 
+// addPackage records that the package with the given path is compiled into
+// the same module as the package for which tm was created, so that the type
+// descriptors of its named types are emitted.
+func (tm *TypeMap) addPackage(pkgpath string) {
+	tm.pkgpaths[pkgpath] = true
+}
+
 func (tm *TypeMap) getNamedTypeLinkage(nt *types.Named) (linkage llvm.Linkage, emit bool) {
 	if pkg := nt.Obj().Pkg(); pkg != nil {
 		linkage = llvm.ExternalLinkage
-		emit = pkg.Path() == tm.pkgpath
+		emit = tm.pkgpaths[pkg.Path()]
 	} else {
 		linkage = llvm.LinkOnceODRLinkage
 		emit = true
@@ -1183,7 +1218,7 @@ func (tm *TypeMap) emitTypeDescInitializer(t types.Type, tdi *typeDescInfo) {
 	insts = tm.appendGcInsts(insts, t, 0, 0)
 	insts = append(insts, tm.makeGcInst(gcOpcodeEND))
 
-	i8ptr := llvm.PointerType(llvm.Int8Type(), 0)
+	i8ptr := llvm.PointerType(tm.ctx.Int8Type(), 0)
 	instArray := llvm.ConstArray(i8ptr, insts)
 
 	newGc := llvm.AddGlobal(tm.module, instArray.Type(), "")
@@ -1253,7 +1288,7 @@ func (tm *TypeMap) getStructAlgorithmFunctions(st *types.Struct) (hash, equal ll
 
 	hash = llvm.AddFunction(tm.module, tm.mc.mangleHashFunctionName(st), tm.hashFnType)
 	hash.SetLinkage(llvm.LinkOnceODRLinkage)
-	builder.SetInsertPointAtEnd(llvm.AddBasicBlock(hash, "entry"))
+	builder.SetInsertPointAtEnd(tm.ctx.AddBasicBlock(hash, "entry"))
 	sptr := builder.CreateBitCast(hash.Param(0), llsptrty, "")
 
 	hashval := llvm.ConstNull(tm.inttype)
@@ -1263,7 +1298,7 @@ func (tm *TypeMap) getStructAlgorithmFunctions(st *types.Struct) (hash, equal ll
 		fptr := builder.CreateStructGEP(sptr, i, "")
 		fptr = builder.CreateBitCast(fptr, i8ptr, "")
 
-		fsize := llvm.ConstInt(tm.inttype, uint64(tm.sizes.Sizeof(st.Field(i).Type())), false)
+		fsize := llvm.ConstInt(tm.inttype, uint64(tm.Sizeof(st.Field(i).Type())), false)
 
 		hashcall := builder.CreateCall(fhash, []llvm.Value{fptr, fsize}, "")
 		hashval = builder.CreateMul(hashval, i33, "")
@@ -1274,8 +1309,8 @@ func (tm *TypeMap) getStructAlgorithmFunctions(st *types.Struct) (hash, equal ll
 
 	equal = llvm.AddFunction(tm.module, tm.mc.mangleEqualFunctionName(st), tm.equalFnType)
 	equal.SetLinkage(llvm.LinkOnceODRLinkage)
-	eqentrybb := llvm.AddBasicBlock(equal, "entry")
-	eqretzerobb := llvm.AddBasicBlock(equal, "retzero")
+	eqentrybb := tm.ctx.AddBasicBlock(equal, "entry")
+	eqretzerobb := tm.ctx.AddBasicBlock(equal, "retzero")
 
 	builder.SetInsertPointAtEnd(eqentrybb)
 	s1ptr := builder.CreateBitCast(equal.Param(0), llsptrty, "")
@@ -1290,12 +1325,12 @@ func (tm *TypeMap) getStructAlgorithmFunctions(st *types.Struct) (hash, equal ll
 		f2ptr := builder.CreateStructGEP(s2ptr, i, "")
 		f2ptr = builder.CreateBitCast(f2ptr, i8ptr, "")
 
-		fsize := llvm.ConstInt(tm.inttype, uint64(tm.sizes.Sizeof(st.Field(i).Type())), false)
+		fsize := llvm.ConstInt(tm.inttype, uint64(tm.Sizeof(st.Field(i).Type())), false)
 
 		equalcall := builder.CreateCall(fequal, []llvm.Value{f1ptr, f2ptr, fsize}, "")
 		equaleqzero := builder.CreateICmp(llvm.IntEQ, equalcall, zerobool, "")
 
-		contbb := llvm.AddBasicBlock(equal, "cont")
+		contbb := tm.ctx.AddBasicBlock(equal, "cont")
 		builder.CreateCondBr(equaleqzero, eqretzerobb, contbb)
 
 		builder.SetInsertPointAtEnd(contbb)
@@ -1325,14 +1360,14 @@ func (tm *TypeMap) getArrayAlgorithmFunctions(at *types.Array) (hash, equal llvm
 
 	i1 := llvm.ConstInt(tm.inttype, 1, false)
 	alen := llvm.ConstInt(tm.inttype, uint64(at.Len()), false)
-	esize := llvm.ConstInt(tm.inttype, uint64(tm.sizes.Sizeof(at.Elem())), false)
+	esize := llvm.ConstInt(tm.inttype, uint64(tm.Sizeof(at.Elem())), false)
 
 	builder := tm.ctx.NewBuilder()
 	defer builder.Dispose()
 
 	hash = llvm.AddFunction(tm.module, tm.mc.mangleHashFunctionName(at), tm.hashFnType)
 	hash.SetLinkage(llvm.LinkOnceODRLinkage)
-	hashentrybb := llvm.AddBasicBlock(hash, "entry")
+	hashentrybb := tm.ctx.AddBasicBlock(hash, "entry")
 	builder.SetInsertPointAtEnd(hashentrybb)
 	if at.Len() == 0 {
 		builder.CreateRet(llvm.ConstNull(tm.inttype))
@@ -1340,10 +1375,10 @@ func (tm *TypeMap) getArrayAlgorithmFunctions(at *types.Array) (hash, equal llvm
 		i33 := llvm.ConstInt(tm.inttype, 33, false)
 
 		aptr := builder.CreateBitCast(hash.Param(0), llelemty, "")
-		loopbb := llvm.AddBasicBlock(hash, "loop")
+		loopbb := tm.ctx.AddBasicBlock(hash, "loop")
 		builder.CreateBr(loopbb)
 
-		exitbb := llvm.AddBasicBlock(hash, "exit")
+		exitbb := tm.ctx.AddBasicBlock(hash, "exit")
 
 		builder.SetInsertPointAtEnd(loopbb)
 		indexphi := builder.CreatePHI(tm.inttype, "")
@@ -1381,18 +1416,18 @@ func (tm *TypeMap) getArrayAlgorithmFunctions(at *types.Array) (hash, equal llvm
 
 	equal = llvm.AddFunction(tm.module, tm.mc.mangleEqualFunctionName(at), tm.equalFnType)
 	equal.SetLinkage(llvm.LinkOnceODRLinkage)
-	eqentrybb := llvm.AddBasicBlock(equal, "entry")
+	eqentrybb := tm.ctx.AddBasicBlock(equal, "entry")
 	builder.SetInsertPointAtEnd(eqentrybb)
 	if at.Len() == 0 {
 		builder.CreateRet(onebool)
 	} else {
 		a1ptr := builder.CreateBitCast(equal.Param(0), llelemty, "")
 		a2ptr := builder.CreateBitCast(equal.Param(1), llelemty, "")
-		loopbb := llvm.AddBasicBlock(equal, "loop")
+		loopbb := tm.ctx.AddBasicBlock(equal, "loop")
 		builder.CreateBr(loopbb)
 
-		exitbb := llvm.AddBasicBlock(equal, "exit")
-		retzerobb := llvm.AddBasicBlock(equal, "retzero")
+		exitbb := tm.ctx.AddBasicBlock(equal, "exit")
+		retzerobb := tm.ctx.AddBasicBlock(equal, "retzero")
 
 		builder.SetInsertPointAtEnd(loopbb)
 		indexphi := builder.CreatePHI(tm.inttype, "")
@@ -1406,7 +1441,7 @@ func (tm *TypeMap) getArrayAlgorithmFunctions(at *types.Array) (hash, equal llvm
 		equalcall := builder.CreateCall(eequal, []llvm.Value{e1ptr, e2ptr, esize}, "")
 		equaleqzero := builder.CreateICmp(llvm.IntEQ, equalcall, zerobool, "")
 
-		contbb := llvm.AddBasicBlock(equal, "cont")
+		contbb := tm.ctx.AddBasicBlock(equal, "cont")
 		builder.CreateCondBr(equaleqzero, retzerobb, contbb)
 
 		builder.SetInsertPointAtEnd(contbb)
@@ -1484,7 +1519,7 @@ func (tm *TypeMap) getTypeDescInfo(t types.Type) *typeDescInfo {
 	global.SetGlobalConstant(true)
 	ptr := llvm.ConstBitCast(global, llvm.PointerType(tm.commonTypeType, 0))
 
-	gc := llvm.AddGlobal(tm.module, llvm.PointerType(llvm.Int8Type(), 0), b.String()+"$gc")
+	gc := llvm.AddGlobal(tm.module, llvm.PointerType(tm.ctx.Int8Type(), 0), b.String()+"$gc")
 	gc.SetGlobalConstant(true)
 	gcPtr := llvm.ConstBitCast(gc, llvm.PointerType(tm.ctx.Int8Type(), 0))
 
@@ -1538,13 +1573,13 @@ func (tm *TypeMap) getImtPointer(srctype types.Type, targettype *types.Interface
 	}
 
 	srcms := tm.MethodSet(srctype)
-	targetms := tm.MethodSet(targettype)
+	targetms := tm.OrderedMethodSet(targettype)
 
-	i8ptr := llvm.PointerType(llvm.Int8Type(), 0)
+	i8ptr := llvm.PointerType(tm.ctx.Int8Type(), 0)
 
-	elems := make([]llvm.Value, targetms.Len()+1)
+	elems := make([]llvm.Value, len(targetms)+1)
 	elems[0] = tm.ToRuntime(srctype)
-	for i, targetm := range orderedMethodSet(targetms) {
+	for i, targetm := range targetms {
 		srcm := srcms.Lookup(targetm.Obj().Pkg(), targetm.Obj().Name())
 
 		elems[i+1] = tm.methodResolver.ResolveMethod(srcm).value
@@ -1801,7 +1836,7 @@ func (tm *TypeMap) makeFuncType(t types.Type, f *types.Signature) llvm.Value {
 	if f.Variadic() {
 		variadic = 1
 	}
-	vals[1] = llvm.ConstInt(llvm.Int8Type(), uint64(variadic), false)
+	vals[1] = llvm.ConstInt(tm.ctx.Int8Type(), uint64(variadic), false)
 	// in
 	vals[2] = tm.rtypeSlice(f.Params())
 	// out
@@ -1814,9 +1849,9 @@ func (tm *TypeMap) makeInterfaceType(t types.Type, i *types.Interface) llvm.Valu
 	var vals [2]llvm.Value
 	vals[0] = tm.makeCommonType(t)
 
-	methodset := tm.MethodSet(i)
-	imethods := make([]llvm.Value, methodset.Len())
-	for index, ms := range orderedMethodSet(methodset) {
+	methodset := tm.OrderedMethodSet(i)
+	imethods := make([]llvm.Value, len(methodset))
+	for index, ms := range methodset {
 		method := ms.Obj()
 		var imvals [3]llvm.Value
 		imvals[0] = tm.globalStringPtr(method.Name())
@@ -1890,13 +1925,13 @@ func (tm *TypeMap) makeUncommonTypePtr(t types.Type) llvm.Value {
 	_, isbasic := t.(*types.Basic)
 	_, isnamed := t.(*types.Named)
 
-	var mset types.MethodSet
+	var omset []*types.Selection
 	// We store interface methods on the interface type.
 	if _, ok := t.Underlying().(*types.Interface); !ok {
-		mset = *tm.MethodSet(t)
+		omset = tm.OrderedMethodSet(t)
 	}
 
-	if !isbasic && !isnamed && mset.Len() == 0 {
+	if !isbasic && !isnamed && len(omset) == 0 {
 		return llvm.ConstPointerNull(llvm.PointerType(tm.uncommonTypeType, 0))
 	}
 
@@ -1923,8 +1958,7 @@ func (tm *TypeMap) makeUncommonTypePtr(t types.Type) llvm.Value {
 
 	// Store methods. All methods must be stored, not only exported ones;
 	// this is to allow satisfying of interfaces with non-exported methods.
-	methods := make([]llvm.Value, mset.Len())
-	omset := orderedMethodSet(&mset)
+	methods := make([]llvm.Value, len(omset))
 	for i := range methods {
 		var mvals [5]llvm.Value
 
@@ -1976,14 +2010,14 @@ func (tm *TypeMap) makeUncommonTypePtr(t types.Type) llvm.Value {
 
 // globalStringPtr returns a *string with the specified value.
 func (tm *TypeMap) globalStringPtr(value string) llvm.Value {
-	strval := llvm.ConstString(value, false)
+	strval := tm.ctx.ConstString(value, false)
 	strglobal := llvm.AddGlobal(tm.module, strval.Type(), "")
 	strglobal.SetGlobalConstant(true)
 	strglobal.SetLinkage(llvm.InternalLinkage)
 	strglobal.SetInitializer(strval)
-	strglobal = llvm.ConstBitCast(strglobal, llvm.PointerType(llvm.Int8Type(), 0))
+	strglobal = llvm.ConstBitCast(strglobal, llvm.PointerType(tm.ctx.Int8Type(), 0))
 	strlen := llvm.ConstInt(tm.inttype, uint64(len(value)), false)
-	str := llvm.ConstStruct([]llvm.Value{strglobal, strlen}, false)
+	str := tm.ctx.ConstStruct([]llvm.Value{strglobal, strlen}, false)
 	g := llvm.AddGlobal(tm.module, str.Type(), "")
 	g.SetGlobalConstant(true)
 	g.SetLinkage(llvm.InternalLinkage)
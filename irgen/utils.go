@@ -7,8 +7,8 @@ import (
 
 func (fr *frame) loadOrNull(cond, ptr llvm.Value, ty types.Type) *govalue {
 	startbb := fr.builder.GetInsertBlock()
-	loadbb := llvm.AddBasicBlock(fr.function, "")
-	contbb := llvm.AddBasicBlock(fr.function, "")
+	loadbb := fr.llvmtypes.ctx.AddBasicBlock(fr.function, "")
+	contbb := fr.llvmtypes.ctx.AddBasicBlock(fr.function, "")
 	fr.builder.CreateCondBr(cond, loadbb, contbb)
 
 	fr.builder.SetInsertPointAtEnd(loadbb)
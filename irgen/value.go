@@ -47,10 +47,10 @@ func (fr *frame) newValueFromConst(v exact.Value, typ types.Type) *govalue {
 		llvmtyp := fr.types.ToLLVM(typ)
 		strval := exact.StringVal(v)
 		strlen := len(strval)
-		i8ptr := llvm.PointerType(llvm.Int8Type(), 0)
+		i8ptr := llvm.PointerType(fr.llvmtypes.ctx.Int8Type(), 0)
 		var ptr llvm.Value
 		if strlen > 0 {
-			init := llvm.ConstString(strval, false)
+			init := fr.llvmtypes.ctx.ConstString(strval, false)
 			ptr = llvm.AddGlobal(fr.module.Module, init.Type(), "")
 			ptr.SetInitializer(init)
 			ptr.SetLinkage(llvm.InternalLinkage)
@@ -83,7 +83,7 @@ func (fr *frame) newValueFromConst(v exact.Value, typ types.Type) *govalue {
 		if isUntyped(typ) {
 			typ = types.Typ[types.Bool]
 		}
-		return newValue(boolLLVMValue(exact.BoolVal(v)), typ)
+		return newValue(fr.boolLLVMValue(exact.BoolVal(v)), typ)
 
 	case isFloat(typ):
 		if isUntyped(typ) {
@@ -144,7 +144,7 @@ func (fr *frame) binaryOp(lhs *govalue, op token.Token, rhs *govalue) *govalue {
 		// TODO(axw) use runtime equality algorithm (will be suitably inlined).
 		// For now, we use compare all fields unconditionally and bitwise AND
 		// to avoid branching (i.e. so we don't create additional blocks).
-		value := newValue(boolLLVMValue(true), types.Typ[types.Bool])
+		value := newValue(fr.boolLLVMValue(true), types.Typ[types.Bool])
 		for i := 0; i < typ.NumFields(); i++ {
 			t := typ.Field(i).Type()
 			lhs := newValue(b.CreateExtractValue(lhs.value, i, ""), t)
@@ -155,7 +155,7 @@ func (fr *frame) binaryOp(lhs *govalue, op token.Token, rhs *govalue) *govalue {
 
 	case *types.Array:
 		// TODO(pcc): as above.
-		value := newValue(boolLLVMValue(true), types.Typ[types.Bool])
+		value := newValue(fr.boolLLVMValue(true), types.Typ[types.Bool])
 		t := typ.Elem()
 		for i := int64(0); i < typ.Len(); i++ {
 			lhs := newValue(b.CreateExtractValue(lhs.value, int(i), ""), t)
@@ -169,13 +169,13 @@ func (fr *frame) binaryOp(lhs *govalue, op token.Token, rhs *govalue) *govalue {
 		lhsptr := b.CreateExtractValue(lhs.value, 0, "")
 		rhsptr := b.CreateExtractValue(rhs.value, 0, "")
 		isnil := b.CreateICmp(llvm.IntEQ, lhsptr, rhsptr, "")
-		isnil = b.CreateZExt(isnil, llvm.Int8Type(), "")
+		isnil = b.CreateZExt(isnil, fr.llvmtypes.ctx.Int8Type(), "")
 		return newValue(isnil, types.Typ[types.Bool])
 
 	case *types.Signature:
 		// func == nil or nil == func
 		isnil := b.CreateICmp(llvm.IntEQ, lhs.value, rhs.value, "")
-		isnil = b.CreateZExt(isnil, llvm.Int8Type(), "")
+		isnil = b.CreateZExt(isnil, fr.llvmtypes.ctx.Int8Type(), "")
 		return newValue(isnil, types.Typ[types.Bool])
 
 	case *types.Interface:
@@ -246,7 +246,7 @@ func (fr *frame) binaryOp(lhs *govalue, op token.Token, rhs *govalue) *govalue {
 			realeq := b.CreateFCmp(llvm.FloatOEQ, a_, c_, "")
 			imageq := b.CreateFCmp(llvm.FloatOEQ, b_, d_, "")
 			result = b.CreateAnd(realeq, imageq, "")
-			result = b.CreateZExt(result, llvm.Int8Type(), "")
+			result = b.CreateZExt(result, fr.llvmtypes.ctx.Int8Type(), "")
 			return newValue(result, types.Typ[types.Bool])
 		default:
 			panic(fmt.Errorf("unhandled operator: %v", op))
@@ -266,23 +266,17 @@ func (fr *frame) binaryOp(lhs *govalue, op token.Token, rhs *govalue) *govalue {
 		}
 		return newValue(result, lhs.typ)
 	case token.QUO:
-		switch {
-		case isFloat(lhs.typ):
+		if isFloat(lhs.typ) {
 			result = b.CreateFDiv(lhs.value, rhs.value, "")
-		case !isUnsigned(lhs.typ):
-			result = b.CreateSDiv(lhs.value, rhs.value, "")
-		default:
-			result = b.CreateUDiv(lhs.value, rhs.value, "")
+		} else {
+			result = fr.integerDivide(lhs, rhs, op)
 		}
 		return newValue(result, lhs.typ)
 	case token.REM:
-		switch {
-		case isFloat(lhs.typ):
+		if isFloat(lhs.typ) {
 			result = b.CreateFRem(lhs.value, rhs.value, "")
-		case !isUnsigned(lhs.typ):
-			result = b.CreateSRem(lhs.value, rhs.value, "")
-		default:
-			result = b.CreateURem(lhs.value, rhs.value, "")
+		} else {
+			result = fr.integerDivide(lhs, rhs, op)
 		}
 		return newValue(result, lhs.typ)
 	case token.ADD:
@@ -307,7 +301,7 @@ func (fr *frame) binaryOp(lhs *govalue, op token.Token, rhs *govalue) *govalue {
 		} else {
 			result = b.CreateICmp(llvm.IntEQ, lhs.value, rhs.value, "")
 		}
-		result = b.CreateZExt(result, llvm.Int8Type(), "")
+		result = b.CreateZExt(result, fr.llvmtypes.ctx.Int8Type(), "")
 		return newValue(result, types.Typ[types.Bool])
 	case token.LSS:
 		switch {
@@ -318,7 +312,7 @@ func (fr *frame) binaryOp(lhs *govalue, op token.Token, rhs *govalue) *govalue {
 		default:
 			result = b.CreateICmp(llvm.IntULT, lhs.value, rhs.value, "")
 		}
-		result = b.CreateZExt(result, llvm.Int8Type(), "")
+		result = b.CreateZExt(result, fr.llvmtypes.ctx.Int8Type(), "")
 		return newValue(result, types.Typ[types.Bool])
 	case token.LEQ:
 		switch {
@@ -329,7 +323,7 @@ func (fr *frame) binaryOp(lhs *govalue, op token.Token, rhs *govalue) *govalue {
 		default:
 			result = b.CreateICmp(llvm.IntULE, lhs.value, rhs.value, "")
 		}
-		result = b.CreateZExt(result, llvm.Int8Type(), "")
+		result = b.CreateZExt(result, fr.llvmtypes.ctx.Int8Type(), "")
 		return newValue(result, types.Typ[types.Bool])
 	case token.GTR:
 		switch {
@@ -340,7 +334,7 @@ func (fr *frame) binaryOp(lhs *govalue, op token.Token, rhs *govalue) *govalue {
 		default:
 			result = b.CreateICmp(llvm.IntUGT, lhs.value, rhs.value, "")
 		}
-		result = b.CreateZExt(result, llvm.Int8Type(), "")
+		result = b.CreateZExt(result, fr.llvmtypes.ctx.Int8Type(), "")
 		return newValue(result, types.Typ[types.Bool])
 	case token.GEQ:
 		switch {
@@ -351,7 +345,7 @@ func (fr *frame) binaryOp(lhs *govalue, op token.Token, rhs *govalue) *govalue {
 		default:
 			result = b.CreateICmp(llvm.IntUGE, lhs.value, rhs.value, "")
 		}
-		result = b.CreateZExt(result, llvm.Int8Type(), "")
+		result = b.CreateZExt(result, fr.llvmtypes.ctx.Int8Type(), "")
 		return newValue(result, types.Typ[types.Bool])
 	case token.AND: // a & b
 		result = b.CreateAnd(lhs.value, rhs.value, "")
@@ -373,15 +367,68 @@ func (fr *frame) binaryOp(lhs *govalue, op token.Token, rhs *govalue) *govalue {
 	panic("unreachable")
 }
 
+// integerDivide returns the quotient (if op is token.QUO) or remainder (if op
+// is token.REM) of integers lhs and rhs, as Go defines them: division by zero
+// panics, and dividing the most negative value by -1 yields that value, with
+// a remainder of zero. Neither case may be left to the target, as some do
+// not trap on division by zero (32-bit ARM), and others trap on the latter
+// overflow (x86), which LLVM considers undefined.
+func (fr *frame) integerDivide(lhs, rhs *govalue, op token.Token) llvm.Value {
+	b := fr.builder
+	x, y := lhs.value, rhs.value
+	zero := llvm.ConstNull(y.Type())
+	allOnes := llvm.ConstAllOnes(y.Type())
+	constant := !y.IsAConstantInt().IsNil()
+
+	if !constant || y.ZExtValue() == 0 {
+		isZero := b.CreateICmp(llvm.IntEQ, y, zero, "")
+		fr.condBrRuntimeError(isZero, gccgoRuntimeErrorDIVISION_BY_ZERO)
+	}
+
+	if isUnsigned(lhs.typ) {
+		if op == token.QUO {
+			return b.CreateUDiv(x, y, "")
+		}
+		return b.CreateURem(x, y, "")
+	}
+	if constant && y.SExtValue() != -1 {
+		if op == token.QUO {
+			return b.CreateSDiv(x, y, "")
+		}
+		return b.CreateSRem(x, y, "")
+	}
+
+	// Divide by 1 in place of -1, so as not to overflow, and then negate
+	// the quotient (which wraps for the most negative value) or take a
+	// remainder of zero.
+	isMinusOne := b.CreateICmp(llvm.IntEQ, y, allOnes, "")
+	divisor := b.CreateSelect(isMinusOne, llvm.ConstInt(y.Type(), 1, false), y, "")
+	if op == token.QUO {
+		quo := b.CreateSDiv(x, divisor, "")
+		return b.CreateSelect(isMinusOne, b.CreateNeg(x, ""), quo, "")
+	}
+	rem := b.CreateSRem(x, divisor, "")
+	return b.CreateSelect(isMinusOne, zero, rem, "")
+}
+
 func (fr *frame) shift(lhs *govalue, rhs *govalue, op token.Token) *govalue {
-	rhs = fr.convert(rhs, lhs.Type())
 	lhsval := lhs.value
 	bits := rhs.value
 	unsigned := isUnsigned(lhs.Type())
 	// Shifting >= width of lhs yields undefined behaviour, so we must select.
-	max := llvm.ConstInt(bits.Type(), uint64(lhsval.Type().IntTypeWidth()-1), false)
+	// The count is compared before it is converted to the type of lhs, so
+	// that a count too wide for that type is not truncated to a small one
+	// (for example, an int32 shifted by a uint64 count on a 32-bit target).
+	width := lhsval.Type().IntTypeWidth()
+	lessEqualWidth := fr.builder.CreateICmp(llvm.IntULE, bits, llvm.ConstInt(bits.Type(), uint64(width-1), false), "")
+	switch n := bits.Type().IntTypeWidth() - width; {
+	case n < 0:
+		bits = fr.builder.CreateZExt(bits, lhsval.Type(), "")
+	case n > 0:
+		bits = fr.builder.CreateTrunc(bits, lhsval.Type(), "")
+	}
+	max := llvm.ConstInt(lhsval.Type(), uint64(width-1), false)
 	var result llvm.Value
-	lessEqualWidth := fr.builder.CreateICmp(llvm.IntULE, bits, max, "")
 	if !unsigned && op == token.SHR {
 		bits := fr.builder.CreateSelect(lessEqualWidth, bits, max, "")
 		result = fr.builder.CreateAShr(lhsval, bits, "")
@@ -420,7 +467,7 @@ func (fr *frame) unaryOp(v *govalue, op token.Token) *govalue {
 	case token.ADD:
 		return v // No-op
 	case token.NOT:
-		value := fr.builder.CreateXor(v.value, boolLLVMValue(true), "")
+		value := fr.builder.CreateXor(v.value, fr.boolLLVMValue(true), "")
 		return newValue(value, v.typ)
 	case token.XOR:
 		lhs := v.value
@@ -494,19 +541,7 @@ func (fr *frame) convert(v *govalue, dsttyp types.Type) *govalue {
 
 	// []byte -> string
 	if isSlice(srctyp, types.Byte) && isString(dsttyp) {
-		value := v.value
-		data := fr.builder.CreateExtractValue(value, 0, "")
-		len := fr.builder.CreateExtractValue(value, 1, "")
-
-		// Data must be copied, to prevent changes in
-		// the byte slice from mutating the string.
-		newdata := fr.createMalloc(len, false)
-		fr.memcpy(newdata, data, len)
-
-		struct_ := llvm.Undef(fr.types.ToLLVM(types.Typ[types.String]))
-		struct_ = fr.builder.CreateInsertValue(struct_, newdata, 0, "")
-		struct_ = fr.builder.CreateInsertValue(struct_, len, 1, "")
-		return newValue(struct_, types.Typ[types.String])
+		return fr.byteSliceToString(v, true)
 	}
 
 	// []rune -> string
@@ -603,10 +638,10 @@ func (fr *frame) convert(v *govalue, dsttyp types.Type) *govalue {
 		var fptype llvm.Type
 		if srctyp == types.Typ[types.Complex64] {
 			fpcast = (llvm.Builder).CreateFPExt
-			fptype = llvm.DoubleType()
+			fptype = fr.llvmtypes.ctx.DoubleType()
 		} else {
 			fpcast = (llvm.Builder).CreateFPTrunc
-			fptype = llvm.FloatType()
+			fptype = fr.llvmtypes.ctx.FloatType()
 		}
 		if fpcast != nil {
 			realv := b.CreateExtractValue(lv, 0, "")
@@ -619,7 +654,8 @@ func (fr *frame) convert(v *govalue, dsttyp types.Type) *govalue {
 			return newValue(lv, origdsttyp)
 		}
 	}
-	panic(fmt.Sprintf("unimplemented conversion: %s (%s) -> %s", v.typ, lv.Type(), origdsttyp))
+	fr.errorf(fr.pos, "unsupported conversion: %s (%s) -> %s", v.typ, lv.Type(), origdsttyp)
+	panic("unreachable")
 }
 
 // extractRealValue extracts the real component of a complex number.
@@ -640,9 +676,9 @@ func (fr *frame) extractImagValue(v *govalue) *govalue {
 	return newValue(component, types.Typ[types.Float64])
 }
 
-func boolLLVMValue(v bool) (lv llvm.Value) {
+func (fr *frame) boolLLVMValue(v bool) (lv llvm.Value) {
 	if v {
-		return llvm.ConstInt(llvm.Int8Type(), 1, false)
+		return llvm.ConstInt(fr.llvmtypes.ctx.Int8Type(), 1, false)
 	}
-	return llvm.ConstNull(llvm.Int8Type())
+	return llvm.ConstNull(fr.llvmtypes.ctx.Int8Type())
 }
@@ -0,0 +1,70 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package irgen
+
+import (
+	"go/ast"
+	"strconv"
+
+	"golang.org/x/tools/go/loader"
+	"golang.org/x/tools/go/types"
+)
+
+// vetPackage warns about code in pkginfo that compiles, but is known to
+// misbehave under llgo's runtime, before any code is generated for it:
+// reliance on finalizers, conversions that assume gc's representation of
+// runtime values, and use of package unsafe on targets where the layout
+// computed at compile time may not hold.
+func (c *compiler) vetPackage(pkginfo *loader.PackageInfo) {
+	for _, file := range pkginfo.Files {
+		for _, spec := range file.Imports {
+			path, err := strconv.Unquote(spec.Path.Value)
+			if err == nil && path == "unsafe" && c.pnacl {
+				c.warnf(spec.Pos(), "sizes and offsets computed with package unsafe are those of %s, and may differ on other PNaCl targets", PNaClTriple)
+			}
+		}
+		ast.Inspect(file, func(n ast.Node) bool {
+			if call, ok := n.(*ast.CallExpr); ok {
+				c.vetCall(&pkginfo.Info, call)
+			}
+			return true
+		})
+	}
+}
+
+func (c *compiler) vetCall(info *types.Info, call *ast.CallExpr) {
+	if tv, ok := info.Types[call.Fun]; ok && tv.IsType() {
+		// A conversion. Reading the words of an interface, map, channel or
+		// function value through a pointer converted to unsafe.Pointer
+		// relies on gc's representation of those values, which differs
+		// from libgo's.
+		if !isUnsafePointer(tv.Type) || len(call.Args) != 1 {
+			return
+		}
+		argtype := info.TypeOf(call.Args[0])
+		if argtype == nil {
+			return
+		}
+		if ptr, ok := argtype.Underlying().(*types.Pointer); ok {
+			switch ptr.Elem().Underlying().(type) {
+			case *types.Interface, *types.Map, *types.Chan, *types.Signature:
+				c.warnf(call.Pos(), "conversion of %s to unsafe.Pointer assumes a runtime representation of %s values that differs between gc and llgo", argtype, ptr.Elem())
+			}
+		}
+		return
+	}
+
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return
+	}
+	fn, ok := info.Uses[sel.Sel].(*types.Func)
+	if !ok || fn.Pkg() == nil {
+		return
+	}
+	if fn.Pkg().Path() == "runtime" && fn.Name() == "SetFinalizer" {
+		c.warnf(call.Pos(), "finalizers may never run, as libgo's garbage collector scans conservatively and may retain unreachable objects")
+	}
+}
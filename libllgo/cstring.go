@@ -0,0 +1,61 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+// +build gccgo
+
+package llgo
+
+import "unsafe"
+
+//extern malloc
+func c_malloc(size uintptr) unsafe.Pointer
+
+//extern strlen
+func c_strlen(s unsafe.Pointer) uintptr
+
+// maxCBytes is the length of the byte arrays through which C memory is
+// accessed.
+const maxCBytes = 1<<31 - 1
+
+// cstring implements functions with the cstring attribute. It returns a
+// NUL-terminated copy of s in memory allocated with C's malloc.
+//
+// #llgo name: __llgo_cstring
+func cstring(s string) unsafe.Pointer {
+	p := c_malloc(uintptr(len(s)) + 1)
+	if p == nil {
+		panic("runtime: C malloc failed")
+	}
+	buf := (*[maxCBytes]byte)(p)
+	copy(buf[:len(s)], s)
+	buf[len(s)] = 0
+	return p
+}
+
+// gostring implements functions with the gostring attribute and a single
+// parameter. It returns a copy of the NUL-terminated C string at p, or the
+// empty string if p is nil.
+//
+// #llgo name: __llgo_gostring
+func gostring(p unsafe.Pointer) string {
+	if p == nil {
+		return ""
+	}
+	n := c_strlen(p)
+	return string((*[maxCBytes]byte)(p)[:n])
+}
+
+// gostringn implements functions with the gostring attribute and a length
+// parameter. It returns a copy of the n bytes at p.
+//
+// #llgo name: __llgo_gostringn
+func gostringn(p unsafe.Pointer, n int) string {
+	if n < 0 {
+		panic("runtime: negative length in C string conversion")
+	}
+	if n == 0 {
+		return ""
+	}
+	return string((*[maxCBytes]byte)(p)[:n])
+}
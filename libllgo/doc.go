@@ -0,0 +1,19 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+// +build gccgo
+
+// Package llgo is libllgo, the part of llgo's runtime support that is
+// written in Go rather than provided by libgo.
+//
+// libllgo is compiled by each stage of the llgo bootstrap, with
+// -fgo-pkgpath=llgo, into libllgo.a, which the driver links into every
+// program. It is not imported by Go code: the compiler calls its functions
+// directly, by the C names given to them with "#llgo name:" attributes.
+// Functions of the C library are reached through //extern declarations.
+//
+// The package must not have package-level variables that need
+// initialization, as its init function is never called, and must not
+// import any package but unsafe, as it is compiled before libgo.
+package llgo
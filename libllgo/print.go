@@ -0,0 +1,113 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+// +build gccgo
+
+package llgo
+
+import "unsafe"
+
+//extern __go_print_string
+func printString(s string)
+
+// printDoubleDigits is the number of significant digits printed by
+// printDouble.
+const printDoubleDigits = 7
+
+// stringHeader is the representation of a string.
+type stringHeader struct {
+	data unsafe.Pointer
+	len  int
+}
+
+// printDouble implements print and println for float32 and float64. It
+// prints v in exactly the format used by gc (for example,
+// "+1.234500e+000"), and is a transliteration of gc's runtime·printfloat,
+// including its normalization and rounding steps, so that the digits
+// printed are identical.
+//
+// #llgo name: __llgo_print_double
+func printDouble(v float64) {
+	switch {
+	case v != v:
+		printString("NaN")
+		return
+	case v+v == v && v > 0:
+		printString("+Inf")
+		return
+	case v+v == v && v < 0:
+		printString("-Inf")
+		return
+	}
+
+	var buf [printDoubleDigits + 7]byte
+	buf[0] = '+'
+	e := 0
+	if v == 0 {
+		// Zero keeps its sign, and is printed without normalization.
+		if 1/v < 0 {
+			buf[0] = '-'
+		}
+	} else {
+		if v < 0 {
+			v = -v
+			buf[0] = '-'
+		}
+
+		// Normalize.
+		for v >= 10 {
+			e++
+			v /= 10
+		}
+		for v < 1 {
+			e--
+			v *= 10
+		}
+
+		// Round to the number of digits printed.
+		h := 5.0
+		for i := 0; i < printDoubleDigits; i++ {
+			h /= 10
+		}
+		v += h
+		if v >= 10 {
+			e++
+			v /= 10
+		}
+	}
+
+	// Format as +d.dddddde+ddd.
+	for i := 0; i < printDoubleDigits; i++ {
+		s := int(v)
+		buf[i+2] = byte(s + '0')
+		v -= float64(s)
+		v *= 10
+	}
+	buf[1] = buf[2]
+	buf[2] = '.'
+	buf[printDoubleDigits+2] = 'e'
+	buf[printDoubleDigits+3] = '+'
+	if e < 0 {
+		e = -e
+		buf[printDoubleDigits+3] = '-'
+	}
+	buf[printDoubleDigits+4] = byte(e/100 + '0')
+	buf[printDoubleDigits+5] = byte(e/10%10 + '0')
+	buf[printDoubleDigits+6] = byte(e%10 + '0')
+
+	// Print buf without copying it, as printing must not allocate.
+	hdr := stringHeader{unsafe.Pointer(&buf[0]), len(buf)}
+	printString(*(*string)(unsafe.Pointer(&hdr)))
+}
+
+// printComplex implements print and println for complex64 and complex128,
+// printing c as (real+imagi), as gc does.
+//
+// #llgo name: __llgo_print_complex
+func printComplex(c complex128) {
+	printString("(")
+	printDouble(real(c))
+	printDouble(imag(c))
+	printString("i)")
+}
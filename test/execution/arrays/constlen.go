@@ -0,0 +1,50 @@
+// RUN: llgo -o %t %s
+// RUN: %t > %t1 2>&1
+// RUN: go run %s > %t2 2>&1
+// RUN: diff -u %t1 %t2
+
+package main
+
+import "unsafe"
+
+type header struct {
+	flags uint8
+	size  uint32
+	next  *header
+}
+
+const (
+	base   = 4
+	scaled = base*3 + 1
+	name   = "llgo"
+)
+
+var h header
+
+var (
+	a [scaled]int
+	b [len(name)]byte
+	c [unsafe.Sizeof(h)]byte
+	d [unsafe.Offsetof(h.next) + unsafe.Alignof(h.size)]byte
+	e [len([3]int{}) * base]int16
+)
+
+type buffer [unsafe.Sizeof(uint64(0)) * 2]byte
+
+func main() {
+	println(len(a), len(b), len(c), len(d), len(e))
+	println(unsafe.Sizeof(a), unsafe.Sizeof(c), unsafe.Sizeof(e))
+
+	var buf [unsafe.Sizeof(h.size)]byte
+	for i := range buf {
+		buf[i] = byte(i)
+	}
+	println(len(buf), buf[len(buf)-1])
+
+	var bb buffer
+	bb[len(bb)-1] = 9
+	println(len(bb), bb[15])
+
+	var local [len(name) + scaled]string
+	println(len(local))
+}
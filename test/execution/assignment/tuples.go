@@ -0,0 +1,100 @@
+// RUN: llgo -o %t %s
+// RUN: %t > %t1 2>&1
+// RUN: go run %s > %t2 2>&1
+// RUN: diff -u %t1 %t2
+
+package main
+
+type myint int
+
+type errstr string
+
+func (e errstr) Error() string {
+	return string(e)
+}
+
+func two() (int, error) {
+	return 42, errstr("failed")
+}
+
+func three() (int8, string, float64) {
+	return -3, "three", 3.5
+}
+
+func pairs() []struct{ a, b int } {
+	return []struct{ a, b int }{{1, 2}, {3, 4}}
+}
+
+func main() {
+	// Blank identifiers in declarations and assignments.
+	_, err := two()
+	println(err.Error())
+	n, _ := two()
+	println(n)
+	_, _ = two()
+
+	// Results used in conversions after destructuring.
+	a, s, f := three()
+	println(myint(a), len(s), int(f), float32(a))
+	var x int64
+	var y interface{}
+	x, y, _ = 1, "y", 0
+	println(x, y.(string))
+
+	// Assignment to existing variables with differing static types.
+	var e error
+	var i int
+	i, e = two()
+	println(i, e != nil)
+
+	// Assignment into composite locations.
+	var arr [2]int
+	m := map[string]error{}
+	arr[1], m["k"] = two()
+	println(arr[1], m["k"].Error())
+	st := struct {
+		n int
+		e error
+	}{}
+	st.n, st.e = two()
+	println(st.n, st.e.Error())
+
+	// Comma-ok forms.
+	v, ok := m["k"]
+	println(v != nil, ok)
+	_, ok = m["missing"]
+	println(ok)
+	var iface interface{} = myint(7)
+	mi, ok := iface.(myint)
+	println(mi, ok)
+	_, ok = iface.(string)
+	println(ok)
+	ch := make(chan int, 1)
+	ch <- 5
+	close(ch)
+	r, ok := <-ch
+	println(r, ok)
+	r, ok = <-ch
+	println(r, ok)
+
+	// Range with one or both values discarded.
+	for _, p := range pairs() {
+		println(p.a, p.b)
+	}
+	for i := range pairs() {
+		println(i)
+	}
+
+	// Sending an element of a tuple.
+	ch2 := make(chan int, 1)
+	n, _ = two()
+	ch2 <- n
+	println(<-ch2)
+
+	// Swaps evaluate all right-hand sides first.
+	p, q := 1, 2
+	p, q = q, p
+	println(p, q)
+	arr[0], arr[1] = arr[1], arr[0]
+	println(arr[0], arr[1])
+}
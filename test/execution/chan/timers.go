@@ -0,0 +1,73 @@
+// RUN: llgo -o %t %s
+// RUN: %t > %t1 2>&1
+// RUN: go run %s > %t2 2>&1
+// RUN: diff -u %t1 %t2
+
+package main
+
+import "time"
+
+func timeout() {
+	c := make(chan int)
+	select {
+	case <-c:
+		println("received")
+	case <-time.After(10 * time.Millisecond):
+		println("timed out")
+	}
+}
+
+func noTimeout() {
+	c := make(chan int, 1)
+	c <- 1
+	select {
+	case n := <-c:
+		println("received", n)
+	case <-time.After(time.Hour):
+		println("timed out")
+	}
+}
+
+func tick() {
+	t := time.Tick(time.Millisecond)
+	for i := 0; i < 3; i++ {
+		<-t
+		println("tick", i)
+	}
+}
+
+func stop() {
+	t := time.NewTimer(time.Hour)
+	println("stopped:", t.Stop())
+	select {
+	case <-t.C:
+		println("fired")
+	case <-time.After(10 * time.Millisecond):
+		println("did not fire")
+	}
+}
+
+// Timers are kept in a heap serviced by the scheduler, so many may be
+// pending at once without each occupying a thread.
+func many() {
+	const n = 10000
+	done := make(chan bool)
+	for i := 0; i < n; i++ {
+		go func(d time.Duration) {
+			<-time.After(d)
+			done <- true
+		}(time.Duration(i%10) * time.Millisecond)
+	}
+	for i := 0; i < n; i++ {
+		<-done
+	}
+	println("all", n, "timers fired")
+}
+
+func main() {
+	timeout()
+	noTimeout()
+	tick()
+	stop()
+	many()
+}
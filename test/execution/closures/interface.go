@@ -0,0 +1,63 @@
+// RUN: llgo -o %t %s
+// RUN: %t > %t1 2>&1
+// RUN: go run %s > %t2 2>&1
+// RUN: diff -u %t1 %t2
+
+package main
+
+type Op func(int) int
+
+func (op Op) Apply(x int) int {
+	return op(x)
+}
+
+func (op Op) IsNil() bool {
+	return op == nil
+}
+
+type Applier interface {
+	Apply(int) int
+}
+
+func double(x int) int {
+	return x * 2
+}
+
+func adder(n int) func(int) int {
+	return func(x int) int {
+		return x + n
+	}
+}
+
+func callNil(f func()) {
+	defer func() {
+		err := recover().(error)
+		println(err.Error())
+	}()
+	f()
+}
+
+func main() {
+	var values []interface{}
+	values = append(values, double, adder(3), Op(double), Op(adder(4)))
+	for _, v := range values {
+		switch f := v.(type) {
+		case func(int) int:
+			println("func", f(10), f == nil)
+		case Op:
+			println("Op", f(10), f.Apply(20), f.IsNil())
+		}
+	}
+
+	var a Applier = Op(adder(5))
+	println(a.Apply(1))
+
+	var nilfn func(int) int
+	var e interface{} = nilfn
+	println(e == nil)
+	f := e.(func(int) int)
+	println(f == nil)
+	println(Op(nil).IsNil())
+
+	callNil(nil)
+}
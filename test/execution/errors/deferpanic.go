@@ -0,0 +1,29 @@
+// RUN: llgo -o %t %s
+// RUN: %t > %t1 2>&1
+// RUN: go run %s > %t2 2>&1
+// RUN: diff -u %t1 %t2
+
+package main
+
+func f() {
+	defer func() {
+		println(recover().(string))
+	}()
+	defer panic("deferred panic")
+	println("f")
+}
+
+func g() (x int) {
+	defer func() {
+		recover()
+		x = 123
+	}()
+	panic("g")
+	println("unreachable")
+	return 0
+}
+
+func main() {
+	f()
+	println(g())
+}
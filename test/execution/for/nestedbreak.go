@@ -0,0 +1,75 @@
+// RUN: llgo -o %t %s
+// RUN: %t > %t1 2>&1
+// RUN: go run %s > %t2 2>&1
+// RUN: diff -u %t1 %t2
+
+package main
+
+func switchInLoop() {
+	for i := 0; i < 4; i++ {
+		switch i {
+		case 1:
+			println("break switch", i)
+			break
+			println("unreachable")
+		case 2:
+			println("continue loop", i)
+			continue
+		}
+		println("after switch", i)
+	}
+}
+
+func typeSwitchInLoop() {
+	values := []interface{}{1, "a", 2.5}
+	for _, v := range values {
+		switch v.(type) {
+		case string:
+			break
+		default:
+			println("not a string")
+		}
+		println("after type switch")
+	}
+}
+
+func selectInLoop() {
+	c := make(chan int, 1)
+	for i := 0; i < 3; i++ {
+		select {
+		case c <- i:
+			println("sent", i)
+			break
+			println("unreachable")
+		case n := <-c:
+			println("received", n)
+			if n == 0 {
+				continue
+			}
+		}
+		println("after select", i)
+	}
+}
+
+func labeled() {
+outer:
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			switch {
+			case j == 1:
+				continue outer
+			case i == 2:
+				break outer
+			}
+			println(i, j)
+		}
+	}
+	println("done")
+}
+
+func main() {
+	switchInLoop()
+	typeSwitchInLoop()
+	selectInLoop()
+	labeled()
+}
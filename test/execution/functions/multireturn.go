@@ -0,0 +1,63 @@
+// RUN: llgo -o %t %s
+// RUN: %t > %t1 2>&1
+// RUN: go run %s > %t2 2>&1
+// RUN: diff -u %t1 %t2
+
+package main
+
+type pair struct {
+	a, b int
+}
+
+func (p pair) values() (int, int) {
+	return p.a, p.b
+}
+
+type valuer interface {
+	values() (int, int)
+}
+
+func divmod(a, b int) (int, int) {
+	return a / b, a % b
+}
+
+func forward(a, b int) (int, int) {
+	return divmod(a, b)
+}
+
+func forwardNamed(a, b int) (q, r int) {
+	return divmod(a, b)
+}
+
+func forwardMethod(v valuer) (int, int) {
+	return v.values()
+}
+
+func forwardClosure(f func() (string, error)) (string, error) {
+	return f()
+}
+
+func add(a, b int) int {
+	return a + b
+}
+
+func printpair(a, b int) {
+	println(a, b)
+}
+
+func main() {
+	printpair(forward(17, 5))
+	printpair(forwardNamed(23, 7))
+	printpair(forwardMethod(pair{1, 2}))
+	println(add(pair{3, 4}.values()))
+
+	s, err := forwardClosure(func() (string, error) {
+		return "abc", nil
+	})
+	println(s, err == nil)
+
+	defer printpair(divmod(9, 4))
+	func() {
+		defer printpair(forward(10, 3))
+	}()
+}
@@ -0,0 +1,57 @@
+// RUN: llgo -o %t %s
+// RUN: %t > %t1 2>&1
+// RUN: go run %s > %t2 2>&1
+// RUN: diff -u %t1 %t2
+
+package main
+
+import (
+	"bytes"
+	"runtime"
+	"runtime/pprof"
+	"strings"
+)
+
+var sink [][]byte
+
+//go:noinline
+func allocate() {
+	for i := 0; i < 16; i++ {
+		sink = append(sink, make([]byte, 64<<10))
+	}
+}
+
+// Allocations are sampled by the allocator, and may be dumped in pprof
+// heap profile format with runtime/pprof.
+func main() {
+	runtime.MemProfileRate = 1
+	allocate()
+	// The profile reflects allocations as of the last completed collection.
+	runtime.GC()
+	runtime.GC()
+
+	var records []runtime.MemProfileRecord
+	n, ok := runtime.MemProfile(nil, true)
+	for !ok {
+		records = make([]runtime.MemProfileRecord, n+16)
+		n, ok = runtime.MemProfile(records, true)
+	}
+	records = records[:n]
+
+	var bytesAllocated int64
+	for _, r := range records {
+		for _, pc := range r.Stack() {
+			if f := runtime.FuncForPC(pc); f != nil && strings.HasSuffix(f.Name(), "main.allocate") {
+				bytesAllocated += r.AllocBytes
+				break
+			}
+		}
+	}
+	println("allocation site recorded:", bytesAllocated >= 16*64<<10)
+
+	var buf bytes.Buffer
+	if err := pprof.WriteHeapProfile(&buf); err != nil {
+		println(err.Error())
+	}
+	println("heap profile written:", buf.Len() > 0)
+}
@@ -0,0 +1,66 @@
+// RUN: llgo -o %t %s
+// RUN: %t > %t1 2>&1
+// RUN: go run %s > %t2 2>&1
+// RUN: diff -u %t1 %t2
+
+package main
+
+var calls int
+
+func f() int {
+	calls++
+	println("f()", calls)
+	return calls
+}
+
+func main() {
+	x := 100
+
+	if x := f(); x > 0 {
+		println("if", x)
+	} else {
+		println("else", x)
+	}
+	println("after if", x)
+
+	if x := f(); x > 5 {
+		println("if", x)
+	} else if y := x * 10; y > 15 {
+		println("else if", x, y)
+	} else {
+		println("else", x, y)
+	}
+	println("after else if", x)
+
+	switch x := f(); x {
+	case 3:
+		println("case 3", x)
+		x = 30
+		fallthrough
+	case 4:
+		println("case 4", x)
+	default:
+		println("default", x)
+	}
+	println("after switch", x)
+
+	switch x := f(); {
+	case x > 10:
+		println("x > 10")
+	default:
+		println("default", x)
+	}
+
+	var i interface{} = x
+	switch x := i.(type) {
+	case int:
+		x++
+		println("int", x)
+	}
+	println("after type switch", x)
+
+	for x := f(); x < 8; x = f() {
+		println("for", x)
+	}
+	println("after for", x)
+}
@@ -0,0 +1,57 @@
+// RUN: llgo -o %t %s
+// RUN: %t > %t1 2>&1
+// RUN: go run %s > %t2 2>&1
+// RUN: diff -u %t1 %t2
+
+package main
+
+type Namer interface {
+	Name() string
+}
+
+type Sizer interface {
+	Size() int
+}
+
+type NameSizer interface {
+	Namer
+	Sizer
+}
+
+type A struct{}
+
+func (A) Name() string { return "A" }
+func (A) Size() int    { return 1 }
+
+type B [2]int
+
+func (B) Name() string { return "B" }
+func (B) Size() int    { return 2 }
+
+// Each goroutine makes the first dynamic conversions between these
+// interface types concurrently.
+func worker(v interface{}, results chan int) {
+	total := 0
+	for i := 0; i < 1000; i++ {
+		ns := v.(NameSizer)
+		var n Namer = ns
+		if s, ok := n.(Sizer); ok {
+			total += s.Size()
+		}
+		total += len(n.Name())
+	}
+	results <- total
+}
+
+func main() {
+	results := make(chan int)
+	values := []interface{}{A{}, B{}, A{}, B{}, A{}, B{}, A{}, B{}}
+	for _, v := range values {
+		go worker(v, results)
+	}
+	sum := 0
+	for i := 0; i < len(values); i++ {
+		sum += <-results
+	}
+	println(sum)
+}
@@ -0,0 +1,70 @@
+// RUN: llgo -o %t %s
+// RUN: %t > %t1 2>&1
+// RUN: go run %s > %t2 2>&1
+// RUN: diff -u %t1 %t2
+
+package main
+
+type Shower interface {
+	Show()
+}
+
+type Namer interface {
+	name() string
+}
+
+type ShowNamer interface {
+	Namer
+	Shower
+}
+
+type Val int
+
+func (v Val) Show()        { println("Val", int(v)) }
+func (v Val) name() string { return "val" }
+
+type Ptr int
+
+func (p *Ptr) Show()        { println("Ptr", int(*p)) }
+func (p *Ptr) name() string { return "ptr" }
+
+// EmbedVal has the methods of Val on both EmbedVal and *EmbedVal.
+type EmbedVal struct {
+	Val
+}
+
+// EmbedPtr has the methods of *Ptr only on *EmbedPtr.
+type EmbedPtr struct {
+	Ptr
+}
+
+// EmbedPtrPtr has the methods of *Ptr on EmbedPtrPtr, through the
+// embedded pointer.
+type EmbedPtrPtr struct {
+	*Ptr
+}
+
+func describe(x interface{}) {
+	_, isShower := x.(Shower)
+	_, isNamer := x.(Namer)
+	println(isShower, isNamer)
+	if sn, ok := x.(ShowNamer); ok {
+		sn.Show()
+		var n Namer = sn
+		println(n.name())
+	}
+}
+
+func main() {
+	p := Ptr(2)
+	describe(Val(1))
+	describe(&p)
+	describe(p)
+	describe(EmbedVal{Val(3)})
+	describe(&EmbedVal{Val(4)})
+	describe(EmbedPtr{Ptr(5)})
+	describe(&EmbedPtr{Ptr(6)})
+	describe(EmbedPtrPtr{&p})
+	describe(struct{ Val }{Val(7)})
+	describe(&struct{ Ptr }{Ptr(8)})
+}
@@ -0,0 +1,36 @@
+// RUN: llgo -o %t %s
+// RUN: %t > %t1 2>&1
+// RUN: go run %s > %t2 2>&1
+// RUN: diff -u %t1 %t2
+
+package main
+
+var table = map[string]int{
+	"alpha":    0,
+	"bravo":    1,
+	"charlie":  2,
+	"delta":    3,
+	"echo":     4,
+	"foxtrot":  5,
+	"golf":     6,
+	"hotel":    7,
+	"india":    8,
+	"juliet":   9,
+	"kilo":     10,
+	"lima":     11,
+	"mike":     12,
+	"november": 13,
+	"oscar":    14,
+	"papa":     15,
+	"quebec":   16,
+	"romeo":    17,
+	"sierra":   18,
+	"tango":    19,
+}
+
+func main() {
+	println(len(table))
+	println(table["alpha"], table["kilo"], table["tango"])
+	_, ok := table["zulu"]
+	println(ok)
+}
@@ -0,0 +1,57 @@
+// RUN: llgo -o %t %s
+// RUN: %t > %t1 2>&1
+// RUN: go run %s > %t2 2>&1
+// RUN: diff -u %t1 %t2
+
+package main
+
+type Small struct {
+	a, b int
+}
+
+func (s Small) Mutate() int {
+	s.a = 100
+	return s.a + s.b
+}
+
+type Large struct {
+	a   [32]int
+	tag string
+}
+
+func (l Large) Mutate() int {
+	l.a[0] = 100
+	l.tag = "mutated"
+	return l.a[0] + l.a[31]
+}
+
+type Mutator interface {
+	Mutate() int
+}
+
+func main() {
+	s := Small{1, 2}
+	println(s.Mutate(), s.a, s.b)
+	ps := &s
+	println(ps.Mutate(), ps.a)
+
+	var l Large
+	l.a[31] = 7
+	l.tag = "orig"
+	println(l.Mutate(), l.a[0], l.tag)
+	pl := &l
+	println(pl.Mutate(), pl.a[0], pl.tag)
+
+	// The receiver is copied when the method value is evaluated.
+	f := l.Mutate
+	l.a[31] = 8
+	println(f(), l.a[31])
+
+	var m Mutator = l
+	println(m.Mutate(), l.a[0], l.tag)
+	m = pl
+	println(m.Mutate(), pl.a[0], pl.tag)
+
+	arr := []Large{l}
+	println(arr[0].Mutate(), arr[0].a[0], arr[0].tag)
+}
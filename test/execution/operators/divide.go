@@ -0,0 +1,41 @@
+// RUN: llgo -o %t %s
+// RUN: %t > %t1 2>&1
+// RUN: go run %s > %t2 2>&1
+// RUN: diff -u %t1 %t2
+
+package main
+
+func divide(x, y int64) {
+	defer func() {
+		if r := recover(); r != nil {
+			println("recovered:", r.(error).Error())
+		}
+	}()
+	println(x/y, x%y)
+}
+
+func divide32(x, y int32) {
+	println(x/y, x%y)
+}
+
+func divideUnsigned(x, y uint64) {
+	defer func() {
+		if r := recover(); r != nil {
+			println("recovered:", r.(error).Error())
+		}
+	}()
+	println(x/y, x%y)
+}
+
+func main() {
+	divide(7, 2)
+	divide(-7, 2)
+	divide(7, -1)
+	divide(-1<<63, -1)
+	divide(-1<<63, 1)
+	divide(1, 0)
+	divide32(-1<<31, -1)
+	divide32(-7, -2)
+	divideUnsigned(1<<63, 3)
+	divideUnsigned(1, 0)
+}
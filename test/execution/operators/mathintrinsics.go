@@ -0,0 +1,37 @@
+// RUN: llgo -o %t %s
+// RUN: %t > %t1 2>&1
+// RUN: go run %s > %t2 2>&1
+// RUN: diff -u %t1 %t2
+
+package main
+
+import "math"
+
+func show(f float64) {
+	switch {
+	case f != f:
+		println("NaN")
+	case math.IsInf(f, 1):
+		println("+Inf")
+	case math.IsInf(f, -1):
+		println("-Inf")
+	default:
+		println(int64(f*10), math.Signbit(f))
+	}
+}
+
+func main() {
+	values := []float64{0, math.Copysign(0, -1), 1.5, -2.5, 4, math.Inf(1), math.Inf(-1), math.NaN()}
+	for _, x := range values {
+		show(math.Sqrt(x))
+		show(math.Abs(x))
+		show(math.Floor(x))
+		show(math.Ceil(x))
+		show(math.Trunc(x))
+		show(math.Copysign(3, x))
+		for _, y := range values {
+			show(math.Max(x, y))
+			show(math.Min(x, y))
+		}
+	}
+}
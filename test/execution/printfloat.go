@@ -0,0 +1,21 @@
+// RUN: llgo -o %t %s
+// RUN: %t > %t1 2>&1
+// RUN: go run %s > %t2 2>&1
+// RUN: diff -u %t1 %t2
+
+package main
+
+import "math"
+
+func main() {
+	floats := []float64{
+		0, math.Copysign(0, -1), 1, -1, 1.2345, 10, 0.1, 9.99999999,
+		123456789, 1e300, -1e-300, 5e-324, math.MaxFloat64,
+		math.Inf(1), math.Inf(-1), math.NaN(),
+	}
+	for _, f := range floats {
+		println(f)
+	}
+	println(float32(0.1), float32(-3.5e38))
+	println(complex(1.5, -2), complex64(complex(0, 1)))
+}
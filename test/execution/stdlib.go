@@ -0,0 +1,67 @@
+// RUN: llgo -o %t %s
+// RUN: %t > %t1 2>&1
+// RUN: go run %s > %t2 2>&1
+// RUN: diff -u %t1 %t2
+
+// Exercise the standard library packages that programs most commonly
+// depend on, which are compiled by llgo as part of libgo and installed
+// with it.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type byLength []string
+
+func (s byLength) Len() int           { return len(s) }
+func (s byLength) Less(i, j int) bool { return len(s[i]) < len(s[j]) }
+func (s byLength) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+var errNegative = errors.New("negative")
+
+func parse(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 {
+		return 0, errNegative
+	}
+	return n, nil
+}
+
+func main() {
+	for _, s := range []string{"42", "-1", "x"} {
+		n, err := parse(s)
+		fmt.Println(n, err, err == errNegative)
+	}
+	fmt.Println(strconv.Quote("tab\there"), strconv.FormatFloat(1.5, 'e', 3, 64))
+	fmt.Println(strconv.ParseBool("true"))
+
+	words := strings.Fields("  the quick brown  fox jumps ")
+	fmt.Println(len(words), strings.Join(words, ","))
+	fmt.Println(strings.ToUpper("llgo"), strings.Repeat("ab", 3), strings.Index("chicken", "ken"))
+	fmt.Println(strings.Replace("oink oink oink", "k", "ky", 2), strings.TrimLeft("xxhixx", "x"))
+
+	var buf bytes.Buffer
+	for i := 0; i < 3; i++ {
+		fmt.Fprintf(&buf, "%d;", i)
+	}
+	fmt.Println(buf.String(), bytes.Contains(buf.Bytes(), []byte("1;")))
+	fmt.Println(bytes.Equal(bytes.ToLower([]byte("ABC")), []byte("abc")))
+
+	ints := []int{5, 2, 8, 1, 9}
+	sort.Ints(ints)
+	fmt.Println(ints, sort.SearchInts(ints, 8))
+	sort.Strings(words)
+	fmt.Println(words)
+	sort.Stable(byLength(words))
+	fmt.Println(words)
+}
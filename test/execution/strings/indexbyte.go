@@ -0,0 +1,32 @@
+// RUN: llgo -o %t %s
+// RUN: %t > %t1 2>&1
+// RUN: go run %s > %t2 2>&1
+// RUN: diff -u %t1 %t2
+
+package main
+
+import (
+	"bytes"
+	"strings"
+)
+
+func main() {
+	s := "hello, world"
+	println(strings.IndexByte(s, 'h'))
+	println(strings.IndexByte(s, ','))
+	println(strings.IndexByte(s, 'd'))
+	println(strings.IndexByte(s, 'z'))
+	println(strings.IndexByte("", 'a'))
+	println(strings.IndexByte(s[7:], 'o'))
+
+	b := []byte(s)
+	println(bytes.IndexByte(b, 'w'))
+	println(bytes.IndexByte(b[:5], 'w'))
+	println(bytes.IndexByte(nil, 'a'))
+
+	defer func() {
+		println(recover() != nil)
+	}()
+	i := len(s)
+	println(s[i])
+}
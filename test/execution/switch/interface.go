@@ -0,0 +1,58 @@
+// RUN: llgo -o %t %s
+// RUN: %t > %t1 2>&1
+// RUN: go run %s > %t2 2>&1
+// RUN: diff -u %t1 %t2
+
+package main
+
+type stringer interface {
+	String() string
+}
+
+type N int
+
+func (n N) String() string { return "N" }
+
+func test(x interface{}) {
+	switch x {
+	case nil:
+		println("nil")
+	case 123:
+		println("int 123")
+	case "abc":
+		println("string abc")
+	case N(123):
+		println("N 123")
+	default:
+		println("default")
+	}
+}
+
+func testNonEmpty(s stringer) {
+	switch s {
+	case nil:
+		println("nil stringer")
+	case N(1):
+		println("N 1")
+	default:
+		println("other stringer")
+	}
+}
+
+func main() {
+	test(nil)
+	test(123)
+	test(int64(123))
+	test("abc")
+	test(N(123))
+	test(1.5)
+
+	testNonEmpty(nil)
+	testNonEmpty(N(1))
+	testNonEmpty(N(2))
+
+	var x interface{}
+	println(x == nil, x != nil)
+	x = 0
+	println(x == nil, x != nil)
+}
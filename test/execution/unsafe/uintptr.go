@@ -0,0 +1,36 @@
+// RUN: llgo -O2 -o %t %s
+// RUN: %t > %t1 2>&1
+// RUN: go run %s > %t2 2>&1
+// RUN: diff -u %t1 %t2
+
+package main
+
+import "unsafe"
+
+type T struct {
+	a, b, c int32
+}
+
+func main() {
+	var t T
+	p := unsafe.Pointer(&t)
+
+	pb := (*int32)(unsafe.Pointer(uintptr(p) + unsafe.Offsetof(t.b)))
+	*pb = 123
+	println(t.b)
+
+	pc := (*int32)(unsafe.Pointer(unsafe.Offsetof(t.c) + uintptr(p)))
+	*pc = 456
+	println(t.c)
+
+	pa := (*int32)(unsafe.Pointer(uintptr(unsafe.Pointer(pc)) - 2*unsafe.Sizeof(t.a)))
+	*pa = 789
+	println(t.a)
+
+	var arr [4]int64
+	for i := range arr {
+		e := (*int64)(unsafe.Pointer(uintptr(unsafe.Pointer(&arr[0])) + uintptr(i)*unsafe.Sizeof(arr[0])))
+		*e = int64(i * i)
+	}
+	println(arr[0], arr[1], arr[2], arr[3])
+}
@@ -0,0 +1,12 @@
+package cdep
+
+import "unsafe"
+
+// #llgo cstring
+func CString(s string) *int8
+
+// #llgo gostring
+func GoString(p *int8) string
+
+// #llgo cfuncptr
+func FuncPtr(f func() int32) unsafe.Pointer
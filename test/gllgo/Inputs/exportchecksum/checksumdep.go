@@ -0,0 +1,5 @@
+package checksumdep
+
+func Answer() int {
+	return 42
+}
@@ -0,0 +1,10 @@
+package initdep
+
+import "fmt"
+
+var Greeting string
+
+func init() {
+	Greeting = fmt.Sprint("hello from ", "initdep")
+	println("initdep init")
+}
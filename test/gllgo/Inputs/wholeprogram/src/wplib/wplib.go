@@ -0,0 +1,15 @@
+package wplib
+
+var table = make(map[int]int)
+
+func init() {
+	table[1] = 2
+}
+
+func Used(x int) int {
+	return table[x]
+}
+
+func Unused(x int) int {
+	return x + 1
+}
@@ -0,0 +1,27 @@
+// RUN: rm -rf %t.dir && mkdir -p %t.dir
+// RUN: llgo -c -fgo-pkgpath=cdep -o %t.dir/cdep.o %S/Inputs/bodyless/cdep.go
+// RUN: llgo -I %t.dir -o %t.dir/main %s %t.dir/cdep.o
+// RUN: not %t.dir/main 2>&1 | FileCheck %s
+
+package main
+
+import "cdep"
+
+func one() int32 {
+	return 1
+}
+
+// Functions with the cstring and gostring attributes may be called from
+// other packages, and through func values; those with the cfuncptr
+// attribute panic.
+
+// CHECK: hello
+// CHECK-NEXT: world
+// CHECK-NEXT: panic: cfuncptr function cdep.FuncPtr may only be called directly, from its own package
+
+func main() {
+	println(cdep.GoString(cdep.CString("hello")))
+	cstring := cdep.CString
+	println(cdep.GoString(cstring("world")))
+	cdep.FuncPtr(one)
+}
@@ -0,0 +1,28 @@
+// RUN: not llgo -c -o /dev/null %s 2>&1 | FileCheck %s
+
+package gotest
+
+import "unsafe"
+
+// #llgo cfuncptr
+func callbackPtr(f func() int) unsafe.Pointer
+
+// Signature errors are reported once, at the declaration.
+
+// CHECK: bodylesserrors.go:[[@LINE+2]]:6: cfuncptr function badSig must have a parameter of func type and a pointer result
+// #llgo cfuncptr
+func badSig(f int) unsafe.Pointer
+
+// CHECK: bodylesserrors.go:[[@LINE+2]]:6: cstring attribute applied to function withBody, which has a body
+// #llgo cstring
+func withBody(s string) *int8 { return nil }
+
+// Errors in calls are reported at the call, without repeating a position.
+
+// CHECK: bodylesserrors.go:[[@LINE+3]]:{{[0-9]+}}: gotest.F: cannot convert {{.*}} to a C function pointer
+func F(x int) unsafe.Pointer {
+	badSig(x)
+	return callbackPtr(func() int { return x })
+}
+
+// CHECK-NOT: badSig
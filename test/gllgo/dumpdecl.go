@@ -0,0 +1,50 @@
+// RUN: llgo -S -fdump-decl=F -o - %s | FileCheck --check-prefix=FUNC %s
+// RUN: llgo -S -fdump-decl=T.M -o - %s | FileCheck --check-prefix=METHOD %s
+// RUN: llgo -S -fdump-decl=V -o - %s | FileCheck --check-prefix=GLOBAL %s
+// RUN: llgo -S -fdump-decl=Q -o - %s | FileCheck --check-prefix=QUOTED %s
+// RUN: not llgo -S -fdump-decl=Missing -o - %s 2>&1 | FileCheck --check-prefix=MISSING %s
+
+package gotest
+
+// The function, its closures, and the $recover thunk of the closure that
+// calls recover are all included; other declarations are not.
+
+// FUNC-DAG: define {{.*}} @gotest.F(
+// FUNC-DAG: define {{.*}} @"gotest.F:gotest.F$1"(
+// FUNC-DAG: define {{.*}} @"gotest.F:gotest.F$1$recover"(
+// FUNC-DAG: define {{.*}} @"gotest.F:gotest.F$2"(
+// FUNC-NOT: @gotest.G(
+// FUNC-NOT: @gotest.V =
+func F(x int) int {
+	defer func() {
+		recover()
+	}()
+	add := func(y int) int { return x + y }
+	return add(1)
+}
+
+func G() int {
+	return 1
+}
+
+// METHOD: define {{.*}} @gotest.M.{{.*}}T(
+// METHOD-NOT: define
+type T struct {
+	x int
+}
+
+func (t *T) M() int {
+	return t.x
+}
+
+// GLOBAL: @gotest.V = global
+// GLOBAL-NOT: define
+var V int
+
+// QUOTED: define {{.*}} @"gotest:Q"(
+// QUOTED-NOT: define
+
+// #llgo name: gotest:Q
+func Q() {}
+
+// MISSING: gllgo: error: no function, method or variable named Missing
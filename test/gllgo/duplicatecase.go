@@ -0,0 +1,40 @@
+// RUN: not llgo -c -o /dev/null %s 2>&1 | FileCheck %s
+
+package gotest
+
+type T struct{}
+
+const two = 2
+
+func F(x int, s string, f float64) {
+	switch x {
+	case 1, two:
+	// CHECK: duplicatecase.go:[[@LINE+1]]:7: duplicate case 1 + 1 in switch (previous case at {{.*}}duplicatecase.go:11:10)
+	case 1 + 1:
+	}
+	switch s {
+	case "a", "b":
+	// CHECK: duplicatecase.go:[[@LINE+1]]:12: duplicate case "b" in switch
+	case "c", "b":
+	}
+	switch f {
+	case 0.5:
+	// CHECK: duplicatecase.go:[[@LINE+1]]:7: duplicate case 1.0 / 2 in switch
+	case 1.0 / 2:
+	}
+	// Boolean constants are not checked.
+	switch {
+	case true:
+	case true:
+	}
+}
+
+func G(i interface{}) {
+	switch i.(type) {
+	case int, nil:
+	// CHECK: duplicatecase.go:[[@LINE+1]]:7: duplicate case nil in type switch
+	case nil:
+	// CHECK: duplicatecase.go:[[@LINE+1]]:11: duplicate case int in type switch
+	case *T, int:
+	}
+}
@@ -0,0 +1,15 @@
+// RUN: not llgo -c -o /dev/null %s 2>&1 | FileCheck %s
+
+package gotest
+
+// CHECK: duplicatesymbol.go:13:6: G: symbol dup is also defined by F at {{.*}}duplicatesymbol.go:8:6
+
+// #llgo name: dup
+func F() int {
+	return 1
+}
+
+// #llgo name: dup
+func G() int {
+	return 2
+}
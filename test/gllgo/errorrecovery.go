@@ -0,0 +1,24 @@
+// RUN: not llgo -c -o /dev/null %s 2>&1 | FileCheck %s
+
+package gotest
+
+import "unsafe"
+
+// #llgo cfuncptr
+func callbackPtr(f func() int) unsafe.Pointer
+
+// Both errors are reported, not just the first, and the functions are
+// replaced by stubs that trap.
+
+// CHECK: errorrecovery.go:18:{{[0-9]+}}: gotest.F: cannot convert {{.*}} to a C function pointer
+// CHECK: errorrecovery.go:23:{{[0-9]+}}: gotest.G: cannot convert {{.*}} to a C function pointer
+
+func F(x int) unsafe.Pointer {
+	f := func() int { return x }
+	return callbackPtr(f)
+}
+
+func G(y int) unsafe.Pointer {
+	g := func() int { return y }
+	return callbackPtr(g)
+}
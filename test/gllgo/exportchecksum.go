@@ -0,0 +1,16 @@
+// RUN: rm -rf %t.dir && mkdir -p %t.dir
+// RUN: llgo -c -fgo-pkgpath=checksumdep -o %t.dir/checksumdep.o %S/Inputs/exportchecksum/checksumdep.go
+// RUN: llgo -c -I %t.dir -o /dev/null %s
+
+// Modify the export data in place, keeping the object file valid.
+// RUN: sed -i -e 's/priority 1;/priority 7;/' %t.dir/checksumdep.o
+// RUN: not llgo -c -I %t.dir -o /dev/null %s 2>&1 | FileCheck %s
+
+// CHECK: exportchecksum.go:[[@LINE+3]]:{{[0-9]+}}: could not import checksumdep ({{.*}}checksumdep.o: export data checksum mismatch
+package main
+
+import "checksumdep"
+
+func main() {
+	println(checksumdep.Answer())
+}
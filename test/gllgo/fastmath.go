@@ -0,0 +1,11 @@
+// RUN: llgo -ffast-math -S -emit-llvm -o - %s | FileCheck %s
+// RUN: llgo -S -emit-llvm -o - %s | FileCheck --check-prefix=DEFAULT %s
+// RUN: llgo -funsafe-math-optimizations -S -emit-llvm -o - %s | FileCheck --check-prefix=DEFAULT %s
+
+package gotest
+
+// CHECK: "unsafe-fp-math"="true"
+// DEFAULT-NOT: "unsafe-fp-math"="true"
+func F(x, y float64) float64 {
+	return x*y + x
+}
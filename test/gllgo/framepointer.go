@@ -0,0 +1,11 @@
+// RUN: llgo -fno-omit-frame-pointer -funwind-tables -S -emit-llvm -o - %s | FileCheck %s
+// RUN: llgo -S -emit-llvm -o - %s | FileCheck --check-prefix=DEFAULT %s
+
+package gotest
+
+// CHECK: attributes {{.*}} uwtable {{.*}}"no-frame-pointer-elim"="true"
+// DEFAULT-NOT: uwtable
+// DEFAULT-NOT: no-frame-pointer-elim
+func F() int {
+	return 1
+}
@@ -0,0 +1,12 @@
+// RUN: llgo -c -I %S/Inputs/exportchecksum -o /dev/null %s
+
+// Export data produced by gccgo, such as that of gccgo's libgo, records a
+// checksum of its own, which llgo accepts without checking.
+
+package main
+
+import "gccgodep"
+
+func main() {
+	println(gccgodep.Answer())
+}
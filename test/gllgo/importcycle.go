@@ -0,0 +1,6 @@
+// RUN: not llgo -fgo-pkgpath=gotest -c -o /dev/null %s 2>&1 | FileCheck %s
+
+package gotest
+
+// CHECK: importcycle.go:{{[0-9]+}}:{{[0-9]+}}: import cycle not allowed: gotest imports itself
+import _ "gotest"
@@ -0,0 +1,25 @@
+// RUN: rm -rf %t.dir && mkdir -p %t.dir
+// RUN: llgo -c -fgo-pkgpath=initdep -o %t.dir/initdep.o %S/Inputs/initatlink/initdep.go
+// RUN: llgo -c -finit-at-link -I %t.dir -o %t.dir/main.o %s
+// RUN: llgo -S -emit-llvm -finit-at-link -I %t.dir -o - %s | FileCheck --check-prefix=IR %s
+// RUN: llgo -finit-at-link -o %t.dir/main %t.dir/main.o %t.dir/initdep.o
+// RUN: %t.dir/main 2>&1 | FileCheck %s
+
+package main
+
+import "initdep"
+
+// With -finit-at-link, __go_init_main is defined when linking instead.
+// IR-NOT: define void @__go_init_main
+
+// CHECK: initdep init
+// CHECK-NEXT: main init
+// CHECK-NEXT: hello from initdep
+
+func init() {
+	println("main init")
+}
+
+func main() {
+	println(initdep.Greeting)
+}
@@ -0,0 +1,28 @@
+// RUN: not llgo -c -o /dev/null -fdump-partial-ir=%t.ll %s
+// RUN: FileCheck %s < %t.ll
+
+package gotest
+
+import "unsafe"
+
+// #llgo cfuncptr
+func callbackPtr(f func() int) unsafe.Pointer
+
+// CHECK: ; llgo: compilation of gotest failed:
+// CHECK-NEXT: ; {{.*}}partialir.go:{{[0-9]+}}:{{[0-9]+}}: gotest.F: {{.*}}C function pointer
+
+// The function that failed is replaced by a trap, which has the same
+// function attributes as any other function, and its partial body kept.
+// CHECK-DAG: define internal {{.*}} @gotest.F.llgo.failed(
+// CHECK-DAG: define {{.*}} @gotest.F({{.*}}) [[ATTRS:#[0-9]+]]
+// CHECK-DAG: define {{.*}} @gotest.G(
+// CHECK: attributes [[ATTRS]] = { {{.*}}"split-stack"
+
+func F(x int) unsafe.Pointer {
+	f := func() int { return x }
+	return callbackPtr(f)
+}
+
+func G(y int) int {
+	return y + 1
+}
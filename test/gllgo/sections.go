@@ -0,0 +1,8 @@
+// RUN: llgo -ffunction-sections -S -o - %s | FileCheck %s
+
+package gotest
+
+// CHECK: .section .text.gotest.F
+func F() int {
+	return 1
+}
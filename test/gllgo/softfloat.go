@@ -0,0 +1,10 @@
+// RUN: llgo -msoft-float -S -emit-llvm -o - %s | FileCheck %s
+// RUN: llgo -S -emit-llvm -o - %s | FileCheck --check-prefix=DEFAULT %s
+
+package gotest
+
+// CHECK: "use-soft-float"="true"
+// DEFAULT-NOT: "use-soft-float"="true"
+func F(x, y float64) float64 {
+	return x*y + x
+}
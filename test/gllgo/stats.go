@@ -0,0 +1,20 @@
+// RUN: llgo -stats -c -o /dev/null %s 2>&1 | FileCheck %s
+
+// CHECK: llgo: compile statistics:
+// CHECK-NEXT: parse {{.*}}ms
+// CHECK-NEXT: typecheck {{.*}}ms
+// CHECK-NEXT: ssa {{.*}}ms
+// CHECK-NEXT: codegen {{.*}}stats.go {{.*}}ms
+// CHECK-NEXT: codegen {{.*}}ms
+// CHECK-NEXT: verify {{.*}}ms
+// CHECK-NEXT: optimize {{.*}}ms
+// CHECK-NEXT: emit {{.*}}ms
+// CHECK-NEXT: total {{.*}}ms
+// CHECK-NEXT: Go heap: {{.*}}MB allocated
+// CHECK-NEXT: peak RSS: {{.*}}MB
+
+package gotest
+
+func F(x int) int {
+	return x * 2
+}
@@ -0,0 +1,40 @@
+// RUN: llgo -c -o %t.o %s
+// RUN: llgosize %t.o | FileCheck %s
+
+package gotest
+
+// CHECK: total code data typedesc typealg funcdesc thunk package
+
+// The columns are total, code, data, typedesc, typealg, funcdesc and thunk.
+// Functions, function literals and methods are code; the recover thunk of
+// the deferred function literal is a thunk.
+// CHECK-DAG: {{^ *[1-9][0-9]* +[1-9][0-9]* +[1-9][0-9]* +[1-9][0-9]* +0 +[1-9][0-9]* +[1-9][0-9]*}}  gotest{{$}}
+
+// The descriptor of *T is not associated with any package.
+// CHECK-DAG: {{^ *[1-9][0-9]* +0 +0 +[1-9][0-9]* +0 +0 +0}}  (unnamed types){{$}}
+
+// CHECK: (all symbols)
+
+func F(x int) int {
+	defer func() {
+		recover()
+	}()
+	add := func(y int) int { return x + y }
+	return add(1)
+}
+
+type T struct {
+	x int
+}
+
+func (t *T) M() int {
+	return t.x
+}
+
+var V int
+
+var FV = F
+
+var I interface{} = T{}
+
+var P interface{} = &T{}
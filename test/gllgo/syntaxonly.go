@@ -0,0 +1,14 @@
+// RUN: not llgo -fsyntax-only %s 2>&1 | FileCheck %s
+// RUN: llgo -fsyntax-only %S/fastmath.go
+
+package gotest
+
+// CHECK: syntaxonly.go:9:9: {{.*}}"x"
+// CHECK: syntaxonly.go:13:2: undeclared name: y
+func F() int {
+	return "x"
+}
+
+func G() {
+	y = 1
+}
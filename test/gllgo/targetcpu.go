@@ -0,0 +1,17 @@
+// RUN: llgo -mattr=+avx -S -o - %s | FileCheck %s
+// RUN: llgo -mcpu=x86-64 -S -o - %s | FileCheck --check-prefix=DEFAULT %s
+// RUN: not llgo -mattr=avx -S -o - %s 2>&1 | FileCheck --check-prefix=INVALID %s
+// RUN: llgo -target x86_64-linux-gnu -march=haswell -S -o - %s | FileCheck %s
+// RUN: llgo -target arm-linux-gnueabihf -march=armv7-a -S -emit-llvm -o - %s | FileCheck --check-prefix=ARM %s
+// RUN: not llgo -target arm-linux-gnueabihf -march=cortex-a8 -S -o - %s 2>&1 | FileCheck --check-prefix=ARMCPU %s
+
+package gotest
+
+// CHECK: vmulsd
+// DEFAULT-NOT: vmulsd
+// INVALID: invalid target attribute 'avx' in '-mattr=avx'
+// ARM: target triple = "armv7-linux-gnueabihf"
+// ARMCPU: invalid ARM architecture in '-march=cortex-a8'
+func F(x, y float64) float64 {
+	return x * y
+}
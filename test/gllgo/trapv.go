@@ -0,0 +1,40 @@
+// RUN: llgo -ftrapv -S -emit-llvm -o - %s | FileCheck %s
+// RUN: llgo -ftrapv -S -emit-llvm -o - %s | FileCheck --check-prefix=WRAP %s
+// RUN: llgo -target i686-linux-gnu -ftrapv -S -emit-llvm -o - %s | FileCheck --check-prefix=I386 %s
+// RUN: llgo -S -emit-llvm -o - %s | FileCheck --check-prefix=DEFAULT %s
+
+package gotest
+
+// CHECK: integer overflow at {{.*}}trapv.go
+// CHECK: call { i32, i1 } @llvm.sadd.with.overflow.i32
+// CHECK: call { i64, i1 } @llvm.smul.with.overflow.i64
+// CHECK-NOT: call {{.*}}with.overflow
+
+// Explicit conversions wrap, as Go defines them to; only the truncations
+// that the compiler inserts, such as of a 64-bit index on a 32-bit target,
+// are checked.
+// WRAP-NOT: integer truncation
+// I386: integer truncation at {{.*}}trapv.go:[[@LINE+22]]
+
+// DEFAULT-NOT: with.overflow
+// DEFAULT-NOT: integer truncation
+
+func Add(x, y int32) int32 {
+	return x + y
+}
+
+func Mul(x, y int64) int64 {
+	return x * y
+}
+
+func AddUnsigned(x, y uint32) uint32 {
+	return x + y
+}
+
+func Narrow(x int64) int8 {
+	return int8(x)
+}
+
+func Index(s []byte, i int64) byte {
+	return s[i]
+}
@@ -0,0 +1,39 @@
+// RUN: llgo -fvet -c -o /dev/null %s 2>&1 | FileCheck %s
+// RUN: llgo -fvet -w -c -o /dev/null %s 2>&1 | FileCheck --check-prefix=NOWARN --allow-empty %s
+// RUN: llgo -c -o /dev/null %s 2>&1 | FileCheck --check-prefix=NOWARN --allow-empty %s
+
+package gotest
+
+import (
+	"runtime"
+	"unsafe"
+)
+
+// NOWARN-NOT: warning
+
+type T struct {
+	buf []byte
+}
+
+func NewT() *T {
+	t := &T{make([]byte, 64)}
+	// CHECK: vet.go:[[@LINE+1]]:2: warning: finalizers may never run
+	runtime.SetFinalizer(t, func(*T) {})
+	return t
+}
+
+func TypeWord(x interface{}) uintptr {
+	// CHECK: vet.go:[[@LINE+1]]:21: warning: conversion of *interface{} to unsafe.Pointer assumes a runtime representation of interface{} values that differs between gc and llgo
+	return *(*uintptr)(unsafe.Pointer(&x))
+}
+
+func CodePointer(f func()) uintptr {
+	// CHECK: vet.go:[[@LINE+1]]:21: warning: conversion of *func() to unsafe.Pointer
+	return *(*uintptr)(unsafe.Pointer(&f))
+}
+
+// Conversions of other pointers are not reported.
+// CHECK-NOT: warning
+func Bytes(p *[4]byte) *uint32 {
+	return (*uint32)(unsafe.Pointer(p))
+}
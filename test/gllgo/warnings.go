@@ -0,0 +1,17 @@
+// RUN: llgo -c -o /dev/null %s 2>&1 | FileCheck %s
+// RUN: llgo -w -c -o /dev/null %s 2>&1 | FileCheck --check-prefix=NOWARN --allow-empty %s
+
+package gotest
+
+// CHECK: warnings.go:10:6: warning: unknown attribute "frobnicate" ignored
+// NOWARN-NOT: warning
+
+// #llgo frobnicate
+func F() {
+}
+
+// CHECK: warnings.go:15:6: warning: heap-allocating 131072 byte value of type [16384]int
+func G() *[16384]int {
+	var a [16384]int
+	return &a
+}
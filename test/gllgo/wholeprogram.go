@@ -0,0 +1,21 @@
+// RUN: env GOPATH=%S/Inputs/wholeprogram llgo -fwhole-program -O0 -S -emit-llvm -o - %s | FileCheck %s
+// RUN: env GOPATH=%S/Inputs/wholeprogram llgo -fwhole-program -O0 -S -emit-llvm -o - %s | FileCheck --check-prefix=DEAD %s
+
+package main
+
+import "wplib"
+
+// CHECK-DAG: define internal {{.*}} @wplib.Used
+// CHECK-DAG: define internal void @wplib..import()
+// CHECK-DAG: define void @main.main()
+
+// CHECK: define void @__go_init_main()
+// CHECK: call void @wplib..import()
+// CHECK: call void @main..import()
+
+// DEAD-NOT: wplib.Unused
+// DEAD-NOT: wplib..llgo_abi
+
+func main() {
+	println(wplib.Used(1))
+}
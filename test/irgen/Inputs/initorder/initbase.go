@@ -0,0 +1,7 @@
+package initbase
+
+var Base []string
+
+func init() {
+	Base = append(Base, "base")
+}
@@ -0,0 +1,5 @@
+package initdep
+
+import "initbase"
+
+var Dep = append(initbase.Base, "dep")
@@ -0,0 +1,13 @@
+// RUN: llgo -S -emit-llvm -o - %s | FileCheck %s
+
+package foo
+
+import "strings"
+
+// CHECK-DAG: @foo..llgo_abi.{{[0-9a-f]+}} = constant i8 0
+// CHECK-DAG: @strings..llgo_abi.{{[0-9a-f]+}} = external constant i8
+// CHECK-DAG: @llvm.used = appending global {{.*}}@strings..llgo_abi.{{[0-9a-f]+}}{{.*}} section "llvm.metadata"
+
+func F(s string) bool {
+	return strings.HasPrefix(s, "foo")
+}
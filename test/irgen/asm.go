@@ -0,0 +1,18 @@
+// RUN: llgo -S -emit-llvm -o - %s | FileCheck %s
+
+package foo
+
+// CHECK: define i64 @foo.Add(i64, i64)
+// CHECK: call i64 asm sideeffect "leaq ($1,$2), $0", "=r,r,r"(i64 %0, i64 %1)
+// #llgo asm: "leaq ($1,$2), $0", "=r,r,r"
+func Add(a, b int64) int64
+
+func F() int64 {
+	Fence()
+	return Add(1, 2)
+}
+
+// CHECK: define void @foo.Fence()
+// CHECK: call void asm sideeffect "mfence", "~{memory}"()
+// #llgo asm: "mfence", "~{memory}"
+func Fence()
@@ -0,0 +1,31 @@
+// RUN: llgo -S -emit-llvm -o - %s | FileCheck %s
+
+package foo
+
+// CHECK-LABEL: define{{.*}} @foo.Lookup
+// CHECK-NOT: call i8* @__go_new_nopointers
+// CHECK: call i8* @__go_map_index
+func Lookup(m map[string]int, b []byte) int {
+	return m[string(b)]
+}
+
+// CHECK-LABEL: define{{.*}} @foo.Equal
+// CHECK-NOT: call i8* @__go_new_nopointers
+// CHECK: call {{.*}} @__go_strcmp
+func Equal(b []byte) bool {
+	return string(b) == "x"
+}
+
+// CHECK-LABEL: define{{.*}} @foo.Insert
+// CHECK: call i8* @__go_new_nopointers
+func Insert(m map[string]int, b []byte) {
+	m[string(b)] = 1
+}
+
+// CHECK-LABEL: define{{.*}} @foo.Modified
+// CHECK: call i8* @__go_new_nopointers
+func Modified(b []byte) bool {
+	s := string(b)
+	b[0] = 'y'
+	return s == "x"
+}
@@ -0,0 +1,24 @@
+// RUN: llgo -S -emit-llvm -o - %s | FileCheck %s
+
+package foo
+
+import "unsafe"
+
+// #llgo cfuncptr
+func callbackPtr(f func(int32) int32) unsafe.Pointer
+
+func double(x int32) int32 {
+	return x * 2
+}
+
+// CHECK: define i8* @foo.F()
+// CHECK: ret i8* bitcast (i32 (i32)* @foo.double to i8*)
+func F() unsafe.Pointer {
+	return callbackPtr(double)
+}
+
+// CHECK: define i8* @foo.G()
+// CHECK: ret i8* bitcast (i32 (i32)* @"foo.G:foo.G$1" to i8*)
+func G() unsafe.Pointer {
+	return callbackPtr(func(x int32) int32 { return x + 1 })
+}
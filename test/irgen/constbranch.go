@@ -0,0 +1,29 @@
+// RUN: llgo -S -emit-llvm -o - %s | FileCheck %s
+
+package foo
+
+const debug = false
+
+const goos = "linux"
+
+func trace()
+
+func plan9Only() int
+
+// CHECK-LABEL: define{{.*}} @foo.F
+// CHECK-NOT: br i1
+// CHECK: ret
+// CHECK-NOT: @foo.trace
+// CHECK-NOT: @foo.plan9Only
+func F(x int) int {
+	if debug {
+		trace()
+	}
+	switch goos {
+	case "plan9":
+		return plan9Only()
+	case "linux":
+		return x + 1
+	}
+	return x
+}
@@ -0,0 +1,62 @@
+// RUN: llgo -S -emit-llvm -o - %s | FileCheck %s
+// RUN: llgo -S -emit-llvm -o - %s | FileCheck --check-prefix=BODY %s
+
+package foo
+
+// #llgo cstring
+func cstring(s string) *int8
+
+// #llgo gostring
+func gostring(p *int8) string
+
+// #llgo gostring
+func gostringn(p *int8, n int32) string
+
+// The functions are also given bodies, for calls through func values. The
+// bodies of cgo's helpers are defined by cgo.
+// BODY-DAG: define internal i8* @foo.cstring(
+// BODY-DAG: define internal {{.*}} @foo.gostringn(
+// BODY-NOT: define {{.*}} @_cgo_foo_Cfunc
+
+// As declared by cgo, which names the C helpers with //extern.
+
+//extern _cgo_foo_Cfunc_CString
+func _Cfunc_CString(s string) *int8
+
+//extern _cgo_foo_Cfunc_GoStringN
+func _Cfunc_GoStringN(p *int8, n int32) string
+
+// CHECK: define i8* @foo.CString
+// CHECK: call i8* @__llgo_cstring(i8* {{.*}}, i64 {{.*}})
+// CHECK-NOT: call {{.*}} @foo.cstring
+func CString(s string) *int8 {
+	return cstring(s)
+}
+
+// CHECK: define {{.*}} @foo.GoString
+// CHECK: call {{.*}} @__llgo_gostring(i8* {{.*}})
+// CHECK-NOT: call {{.*}} @foo.gostring
+func GoString(p *int8) string {
+	return gostring(p)
+}
+
+// CHECK: define {{.*}} @foo.GoStringN
+// CHECK: [[LEN:%.*]] = sext i32 {{.*}} to i64
+// CHECK: call {{.*}} @__llgo_gostringn(i8* {{.*}}, i64 [[LEN]])
+func GoStringN(p *int8, n int32) string {
+	return gostringn(p, n)
+}
+
+// CHECK: define i8* @foo.CgoCString
+// CHECK: call i8* @__llgo_cstring(i8* {{.*}}, i64 {{.*}})
+// CHECK-NOT: call {{.*}} @_cgo_foo_Cfunc_CString
+func CgoCString(s string) *int8 {
+	return _Cfunc_CString(s)
+}
+
+// CHECK: define {{.*}} @foo.CgoGoStringN
+// CHECK: call {{.*}} @__llgo_gostringn(
+// CHECK-NOT: call {{.*}} @_cgo_foo_Cfunc_GoStringN
+func CgoGoStringN(p *int8, n int32) string {
+	return _Cfunc_GoStringN(p, n)
+}
@@ -0,0 +1,15 @@
+// RUN: llgo -S -emit-llvm -o - %s | FileCheck %s
+
+package foo
+
+// CHECK: define double @foo.Dot({{.*}}) [[FAST:#[0-9]+]]
+// #llgo fastmath
+func Dot(a, b []float64) float64 {
+	var sum float64
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+// CHECK: attributes [[FAST]] = {{.*}}"unsafe-fp-math"="true"
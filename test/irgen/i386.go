@@ -0,0 +1,74 @@
+// RUN: llgo -target i686-linux-gnu -S -emit-llvm -o - %s | FileCheck %s
+// RUN: llgo -target i686-linux-gnu -S -emit-llvm -o - %s | FileCheck --check-prefix=ALGS %s
+
+package foo
+
+import "unsafe"
+
+type T struct {
+	a int32
+	b int64
+	c float64
+}
+
+// 64-bit integers and floats are 4-byte aligned on i386.
+
+// CHECK: define {{.*}} @foo.Offsets()
+// CHECK: ret i32 20
+func Offsets() uintptr {
+	var t T
+	return unsafe.Offsetof(t.b) + unsafe.Offsetof(t.c) + unsafe.Alignof(t.b)
+}
+
+// CHECK: define {{.*}} @foo.Size()
+// CHECK: ret i32 20
+func Size() uintptr {
+	return unsafe.Sizeof(T{})
+}
+
+// The count is compared with the width before it is truncated.
+
+// CHECK: define {{.*}} @foo.Shl(
+// CHECK: icmp ule i64 {{.*}}, 31
+// CHECK: trunc i64 {{.*}} to i32
+func Shl(x int32, n uint64) int32 {
+	return x << n
+}
+
+// CHECK: define {{.*}} @foo.Div(
+// CHECK: icmp eq i64 {{.*}}, 0
+// CHECK: icmp eq i64 {{.*}}, -1
+// CHECK: sdiv i64
+func Div(x, y int64) int64 {
+	return x / y
+}
+
+// CHECK: define {{.*}} @foo.DivConst(
+// CHECK-NOT: icmp
+// CHECK: sdiv i32 {{.*}}, 3
+func DivConst(x int) int {
+	return x / 3
+}
+
+// The hash and equality functions of a struct pass each field the size it
+// has on the target: Inner is 12 bytes on i386, not 16.
+
+// ALGS: define linkonce_odr i32 @{{"?}}__go_type_hash_{{[^(]*}}Inner{{[^(]*}}(
+// ALGS: call i32 @{{.*}}(i8* {{.*}}, i32 12)
+// ALGS: define linkonce_odr i8 @{{"?}}__go_type_equal_{{[^(]*}}Inner{{[^(]*}}(
+// ALGS: call i8 @{{.*}}(i8* {{.*}}, i8* {{.*}}, i32 12)
+type Inner struct {
+	a int32
+	b int64
+}
+
+type Key struct {
+	in Inner
+	n  int32
+}
+
+var m = make(map[Key]int)
+
+func Lookup(k Key) int {
+	return m[k]
+}
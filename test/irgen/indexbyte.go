@@ -0,0 +1,11 @@
+// RUN: llgo -S -emit-llvm -o - %s | FileCheck %s
+
+package foo
+
+import "strings"
+
+// CHECK: define {{.*}} @foo.F
+// CHECK: call {{.*}} @memchr
+func F(s string) int {
+	return strings.IndexByte(s, '/')
+}
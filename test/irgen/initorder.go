@@ -0,0 +1,23 @@
+// RUN: rm -rf %t.dir && mkdir -p %t.dir
+// RUN: llgo -c -fgo-pkgpath=initbase -o %t.dir/initbase.o %S/Inputs/initorder/initbase.go
+// RUN: llgo -c -fgo-pkgpath=initdep -I %t.dir -o %t.dir/initdep.o %S/Inputs/initorder/initdep.go
+// RUN: llgo -I %t.dir -S -emit-llvm -o - %s | FileCheck %s
+
+package main
+
+import "initdep"
+
+// Each package is initialized after the packages it imports, directly or
+// indirectly.
+
+// CHECK-LABEL: define void @__go_init_main()
+// CHECK: call void @initbase..import()
+// CHECK: call void @initdep..import()
+// CHECK: call void @main..import()
+// CHECK-NEXT: ret void
+
+var s = initdep.Dep
+
+func main() {
+	println(len(s))
+}
@@ -0,0 +1,15 @@
+// RUN: llgo -S -emit-llvm -o - %s | FileCheck %s
+
+package foo
+
+// CHECK: @foo.Array = global [20 x i64] [i64 0, i64 3,
+var Array = [20]int{0, 3, 6, 9, 12, 15, 18, 21, 24, 27, 30, 33, 36, 39, 42, 45, 48, 51, 54, 57}
+
+// CHECK: internal constant [20 x i64] [i64 0, i64 1, i64 2,
+// CHECK: internal constant [20 x i64] [i64 0, i64 1, i64 4,
+var Map = map[int]int{0: 0, 1: 1, 2: 4, 3: 9, 4: 16, 5: 25, 6: 36, 7: 49, 8: 64, 9: 81, 10: 100, 11: 121, 12: 144, 13: 169, 14: 196, 15: 225, 16: 256, 17: 289, 18: 324, 19: 361}
+
+// CHECK-LABEL: define void @__go_init_foo()
+// CHECK: call i8* @__go_map_index
+// CHECK-NOT: call i8* @__go_map_index
+// CHECK: ret void
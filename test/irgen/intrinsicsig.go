@@ -0,0 +1,22 @@
+// RUN: llgo -fgo-pkgpath=strings -S -emit-llvm -o - %s | FileCheck %s
+
+// A function is only replaced by an intrinsic if its signature matches that
+// of the library function, and not merely its package path and name.
+
+package strings
+
+func IndexByte(s string, c rune) int {
+	for i, r := range s {
+		if r == c {
+			return i
+		}
+	}
+	return -1
+}
+
+// CHECK: define {{.*}} @strings.F
+// CHECK: call {{.*}} @strings.IndexByte
+// CHECK-NOT: @memchr
+func F(s string) int {
+	return IndexByte(s, 'x')
+}
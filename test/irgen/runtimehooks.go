@@ -0,0 +1,56 @@
+// RUN: llgo -fruntime-hooks -S -emit-llvm -o - %s | FileCheck %s
+// RUN: llgo -S -emit-llvm -o - %s | FileCheck --check-prefix=NOHOOKS %s
+// RUN: llgo -fruntime-hooks -S -emit-llvm -o - %s | FileCheck --check-prefix=NOTMAIN %s
+// RUN: llgo -fruntime-hooks -S -emit-llvm -o - %S/Inputs/runtimehooks/main.go | FileCheck --check-prefix=MAIN %s
+
+package foo
+
+// NOHOOKS-NOT: __llgo_hook
+
+// CHECK: define weak i8* @__llgo_hook_new(i64
+// CHECK: call i8* @__go_new(
+// CHECK: define weak i8* @__llgo_hook_new_nopointers(i64
+// CHECK: call i8* @__go_new_nopointers(
+// CHECK: define weak void @__llgo_hook_go(i8*
+// CHECK: call void @__go_go(
+// CHECK: define weak void @__llgo_hook_panic(
+// CHECK: call void @__go_panic(
+// CHECK-NEXT: unreachable
+
+// Only the main package defines the hooks on libgo's thread creation and
+// output.
+// NOTMAIN-NOT: __llgo_hook_create_thread
+// NOTMAIN-NOT: __llgo_hook_write
+
+// MAIN: define weak i32 @__llgo_hook_create_thread(i8*
+// MAIN: call i32 @__real_pthread_create(
+// MAIN: define weak i32 @__wrap_pthread_create(i8*
+// MAIN: call i32 @__llgo_hook_create_thread(
+// MAIN: define weak i32 @__llgo_hook_write(i64
+// MAIN: call i32 @__real_runtime_write(
+// MAIN: define weak i32 @__wrap_runtime_write(i64
+// MAIN: call i32 @__llgo_hook_write(
+
+// CHECK: define {{.*}} @foo.F
+// CHECK: call i8* @__llgo_hook_new(
+func F() *[]*int {
+	return new([]*int)
+}
+
+// CHECK: define {{.*}} @foo.G
+// CHECK: call i8* @__llgo_hook_new_nopointers(
+func G() *int {
+	return new(int)
+}
+
+// CHECK: define {{.*}} @foo.H
+// CHECK: call void @__llgo_hook_go(
+func H(f func()) {
+	go f()
+}
+
+// CHECK: define {{.*}} @foo.I
+// CHECK: @__llgo_hook_panic(
+func I() {
+	panic("I")
+}
@@ -0,0 +1,35 @@
+// RUN: llgo -fgc-stack-roots -S -emit-llvm -o - %s | FileCheck %s
+// RUN: llgo -S -emit-llvm -o - %s | FileCheck --check-prefix=NOROOTS %s
+
+package foo
+
+// NOROOTS-NOT: asm sideeffect "", "r,~{memory}"
+
+type T struct {
+	next *T
+	n    int
+}
+
+// CHECK: define {{.*}} @foo.Last(
+// CHECK-NOT: gc "shadow-stack"
+// CHECK: prologue:
+// CHECK: [[SLOT:%[0-9]+]] = alloca
+// CHECK: store {{.*}} null, {{.*}} [[SLOT]]
+// CHECK: call void asm sideeffect "", "r,~{memory}"(i8*
+// CHECK: store {{.*}}, {{.*}}** [[SLOT]]
+// CHECK: }
+func Last(t *T) int {
+	for t.next != nil {
+		t = t.next
+	}
+	return t.n
+}
+
+// Values without pointers are not stored to stack slots.
+
+// CHECK: define {{.*}} @foo.Sum(
+// CHECK-NOT: asm sideeffect
+// CHECK: }
+func Sum(a, b int) int {
+	return a*b + b
+}
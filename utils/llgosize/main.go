@@ -0,0 +1,169 @@
+// Copyright 2014 The llgo Authors.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+// llgosize reports the size of the symbols in an ELF binary produced by
+// llgo, broken down by Go package and by the kind of code or data that
+// llgo emitted for each symbol (functions, type descriptors, thunks, and so
+// on). It is intended to help diagnose binary bloat.
+//
+// Usage:
+//
+//	llgosize [-syms N] binary
+package main
+
+import (
+	"debug/elf"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/go-llvm/llgo/irgen"
+)
+
+type symKind int
+
+const (
+	kindCode = symKind(iota)
+	kindData
+	kindTypeDesc
+	kindTypeAlg
+	kindFuncDesc
+	kindThunk
+	kindCount
+)
+
+var kindNames = [kindCount]string{
+	kindCode:     "code",
+	kindData:     "data",
+	kindTypeDesc: "typedesc",
+	kindTypeAlg:  "typealg",
+	kindFuncDesc: "funcdesc",
+	kindThunk:    "thunk",
+}
+
+// classify returns the package that a symbol originated from, and the kind
+// of symbol it is. Package paths are returned in mangled form, that is,
+// with '/' and '.' replaced by '_'.
+func classify(name string, isFunc bool) (pkg string, kind symKind) {
+	kind = kindData
+	if isFunc {
+		kind = kindCode
+	}
+
+	sym := irgen.ParseSymbol(name)
+	switch {
+	case sym.Descriptor:
+		kind = kindFuncDesc
+	case sym.Recover:
+		kind = kindThunk
+	}
+	switch sym.Kind {
+	case irgen.TypeInfoSymbol:
+		kind = kindTypeDesc
+		if sym.Package == "" {
+			// Unnamed and predeclared types are not associated with
+			// any package.
+			return "(unnamed types)", kind
+		}
+	case irgen.TypeAlgSymbol:
+		return "(type algorithms)", kindTypeAlg
+	case irgen.RuntimeSymbol:
+		return "(runtime)", kind
+	case irgen.CSymbol:
+		return "(other)", kind
+	}
+	return sym.Package, kind
+}
+
+type pkgSizes struct {
+	name  string
+	total uint64
+	kinds [kindCount]uint64
+}
+
+type byTotal []*pkgSizes
+
+func (s byTotal) Len() int           { return len(s) }
+func (s byTotal) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byTotal) Less(i, j int) bool { return s[i].total > s[j].total }
+
+type bySize []elf.Symbol
+
+func (s bySize) Len() int           { return len(s) }
+func (s bySize) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s bySize) Less(i, j int) bool { return s[i].Size > s[j].Size }
+
+func main() {
+	nsyms := flag.Int("syms", 0, "also list the `N` largest symbols")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: llgosize [-syms N] binary")
+		os.Exit(2)
+	}
+
+	f, err := elf.Open(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "llgosize: %v\n", err)
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	syms, err := f.Symbols()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "llgosize: %v\n", err)
+		os.Exit(1)
+	}
+
+	pkgs := make(map[string]*pkgSizes)
+	var total uint64
+	var sized []elf.Symbol
+	for _, sym := range syms {
+		typ := elf.ST_TYPE(sym.Info)
+		if sym.Size == 0 || (typ != elf.STT_FUNC && typ != elf.STT_OBJECT) {
+			continue
+		}
+		pkg, kind := classify(sym.Name, typ == elf.STT_FUNC)
+		ps, ok := pkgs[pkg]
+		if !ok {
+			ps = &pkgSizes{name: pkg}
+			pkgs[pkg] = ps
+		}
+		ps.total += sym.Size
+		ps.kinds[kind] += sym.Size
+		total += sym.Size
+		sized = append(sized, sym)
+	}
+
+	sorted := make([]*pkgSizes, 0, len(pkgs))
+	for _, ps := range pkgs {
+		sorted = append(sorted, ps)
+	}
+	sort.Sort(byTotal(sorted))
+
+	fmt.Printf("%10s", "total")
+	for _, name := range kindNames {
+		fmt.Printf(" %10s", name)
+	}
+	fmt.Println("  package")
+	for _, ps := range sorted {
+		fmt.Printf("%10d", ps.total)
+		for _, size := range ps.kinds {
+			fmt.Printf(" %10d", size)
+		}
+		fmt.Printf("  %s\n", ps.name)
+	}
+	fmt.Printf("%10d  (all symbols)\n", total)
+
+	if *nsyms > 0 {
+		sort.Sort(bySize(sized))
+		if len(sized) > *nsyms {
+			sized = sized[:*nsyms]
+		}
+		fmt.Println()
+		for _, sym := range sized {
+			fmt.Printf("%10d  %s\n", sym.Size, sym.Name)
+		}
+	}
+}